@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
@@ -72,9 +73,38 @@ func attrError(expectedType, attrName string, value interface{}) *Error {
 
 type attributes map[string]interface{}
 
-func (a attributes) encode() (string, error) {
+// attributeOrder lists attribute names in the order in which they are
+// encoded.
+type attributeOrder []string
+
+// names returns the names of the attributes, ordered by their first
+// occurrence in order, followed by the unlisted names in lexical order.
+func (a attributes) names(order attributeOrder) []string {
+	names := make([]string, 0, len(a))
+	listed := make(map[string]bool, len(a))
+	for _, name := range order {
+		if a.has(name) && !listed[name] {
+			names = append(names, name)
+			listed[name] = true
+		}
+	}
+
+	rest := len(names)
+	for name := range a {
+		if !listed[name] {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names[rest:])
+
+	return names
+}
+
+func (a attributes) encode(order attributeOrder) (string, error) {
 	pairs := make([]string, 0, len(a))
-	for name, value := range a {
+	for _, name := range a.names(order) {
+		value := a[name]
 		if value == nil {
 			continue
 		}
@@ -170,7 +200,11 @@ func (a attributes) float(name string) (float64, error) {
 		return 0, err
 	}
 
-	if f, ok := v.(unsignedFloat); ok {
+	switch f := v.(type) {
+	case unsignedFloat:
+		return float64(f), nil
+	case uint64:
+		// a decimal-integer is also a valid decimal-floating-point
 		return float64(f), nil
 	}
 
@@ -183,10 +217,13 @@ func (a attributes) signedFloat(name string) (float64, error) {
 		return 0, err
 	}
 
-	if f, ok := v.(unsignedFloat); ok {
+	switch f := v.(type) {
+	case unsignedFloat:
 		return float64(f), nil
-	} else if f, ok := v.(float64); ok {
+	case float64:
 		return f, nil
+	case uint64:
+		return float64(f), nil
 	}
 
 	return 0, attrError(typeSignedDecimalFloatingPoint, name, v)
@@ -243,16 +280,25 @@ var (
 )
 
 func parseAttributeList(str string) (attributes, error) {
+	attrs, _, err := parseOrderedAttributeList(str)
+	return attrs, err
+}
+
+// parseOrderedAttributeList parses an attribute list and also returns the
+// names of its attributes in the order in which they appear.
+func parseOrderedAttributeList(str string) (attributes, attributeOrder, error) {
 	attrs := make(map[string]interface{})
+	var order attributeOrder
 	for pos := 0; pos < len(str); {
 		name := rxAttributeName.FindString(str[pos:])
 		if name == "" {
-			return nil, ErrBadAttrName
+			return nil, nil, ErrBadAttrName
 		}
 
 		pos += len(name)
 
 		name = name[:len(name)-1]
+		order = append(order, name)
 
 		var matches []string
 		if matches = rxDecimalInteger.FindStringSubmatch(str[pos:]); matches != nil {
@@ -280,13 +326,13 @@ func parseAttributeList(str string) (attributes, error) {
 		} else if matches = rxEnumeratedString.FindStringSubmatch(str[pos:]); matches != nil {
 			attrs[name] = enumeratedString(matches[1])
 		} else {
-			return nil, ErrBadAttrSyntax
+			return nil, nil, ErrBadAttrSyntax
 		}
 
 		pos += len(matches[0])
 	}
 
-	return attrs, nil
+	return attrs, order, nil
 }
 
 func isMissingAttr(err error) bool {
@@ -26,13 +26,43 @@ type Start struct {
 	Precise bool
 }
 
+func parseStart(meta string) (*Start, error) {
+	attrs, err := parseAttributeList(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var start Start
+	timeOffset, err := attrs.signedFloat(attrTimeOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	start.TimeOffset = secondsToDuration(timeOffset)
+
+	precise, err := attrs.enum(attrPrecise)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		switch precise {
+		case "YES":
+			start.Precise = true
+		case "NO":
+		default:
+			return nil, &invalidAttributeValueError{attrPrecise}
+		}
+	}
+
+	return &start, nil
+}
+
 func (s *Start) attrs() (attributes, error) {
 	attrs := attributes{
 		attrTimeOffset: s.TimeOffset.Seconds(),
 	}
 
 	if s.Precise {
-		attrs[attrPrecise] = true
+		attrs[attrPrecise] = enumeratedString("YES")
 	}
 
 	return attrs, nil
@@ -51,15 +81,28 @@ type GenericPlaylist struct {
 	// Start is OPTIONAL.
 	Start *Start
 
+	// Definitions provide variables that can be referenced by URI lines and
+	// quoted-string attribute values.
+	//
+	// Definitions is OPTIONAL.
+	Definitions []*Definition
+
 	Version int
+
+	// Layout records the lines of the decoded tags, and their attribute order
+	// if the playlist was decoded in lossless mode. It is nil for playlists
+	// that were not decoded.
+	Layout *Layout
 }
 
-func (p *GenericPlaylist) encode(w io.Writer) error {
+// encode writes the header of the playlist with the given compatibility
+// version number.
+func (p *GenericPlaylist) encode(w io.Writer, e *Encoder, version int) error {
 	if _, err := fmt.Fprintln(w, headerTag); err != nil {
 		return err
 	}
 
-	if _, err := fmt.Fprintf(w, versionTag+":%d\n", p.Version); err != nil {
+	if _, err := fmt.Fprintf(w, versionTag+":%d\n", version); err != nil {
 		return err
 	}
 
@@ -75,7 +118,7 @@ func (p *GenericPlaylist) encode(w io.Writer) error {
 			return err
 		}
 
-		encodedAttrs, err := attrs.encode()
+		encodedAttrs, err := attrs.encode(e.attributeOrder(startTag, p.Start))
 		if err != nil {
 			return err
 		}
@@ -85,5 +128,21 @@ func (p *GenericPlaylist) encode(w io.Writer) error {
 		}
 	}
 
+	for _, def := range p.Definitions {
+		attrs, err := def.attrs()
+		if err != nil {
+			return err
+		}
+
+		encodedAttrs, err := attrs.encode(e.attributeOrder(defineTag, def))
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(w, defineTag+":"+encodedAttrs); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -15,6 +15,9 @@ const (
 	mapTag             = tagPrefix + "-X-MAP"
 	programDateTimeTag = tagPrefix + "-X-PROGRAM-DATE-TIME"
 	daterangeTag       = tagPrefix + "-X-DATERANGE"
+	partTag            = tagPrefix + "-X-PART"
+	gapTag             = tagPrefix + "-X-GAP"
+	bitrateTag         = tagPrefix + "-X-BITRATE"
 
 	// media playlist tags
 	targetdurationTag        = tagPrefix + "-X-TARGETDURATION"
@@ -23,6 +26,11 @@ const (
 	endlistTag               = tagPrefix + "-X-ENDLIST"
 	playlistTypeTag          = tagPrefix + "-X-PLAYLIST-TYPE"
 	iFramesOnlyTag           = tagPrefix + "-X-I-FRAMES-ONLY"
+	partInfTag               = tagPrefix + "-X-PART-INF"
+	serverControlTag         = tagPrefix + "-X-SERVER-CONTROL"
+	preloadHintTag           = tagPrefix + "-X-PRELOAD-HINT"
+	renditionReportTag       = tagPrefix + "-X-RENDITION-REPORT"
+	skipTag                  = tagPrefix + "-X-SKIP"
 
 	// master playlist tags
 	mediaTag           = tagPrefix + "-X-MEDIA"
@@ -30,53 +38,103 @@ const (
 	iFrameStreamInfTag = tagPrefix + "-X-I-FRAME-STREAM-INF"
 	sessionDataTag     = tagPrefix + "-X-SESSION-DATA"
 	sessionKeyTag      = tagPrefix + "-X-SESSION-KEY"
+	contentSteeringTag = tagPrefix + "-X-CONTENT-STEERING"
 
 	// media or master playlist tags
 	independentSegmentsTag = tagPrefix + "-X-INDEPENDENT-SEGMENTS"
 	startTag               = tagPrefix + "-X-START"
+	defineTag              = tagPrefix + "-X-DEFINE"
 )
 
 const (
-	attrAssocLanguage     = "ASSOC-LANGUAGE"
-	attrAudio             = "AUDIO"
-	attrAutoselect        = "AUTOSELECT"
-	attrAverageBandwidth  = "AVERAGE-BANDWIDTH"
-	attrBandwidth         = "BANDWIDTH"
-	attrByteRange         = "BYTERANGE"
-	attrClass             = "CLASS"
-	attrCharacteristics   = "CHARACTERISTICS"
-	attrChannels          = "CHANNELS"
-	attrClosedCaptions    = "CLOSED-CAPTIONS"
-	attrCodecs            = "CODECS"
-	attrDataID            = "DATA-ID"
-	attrDefault           = "DEFAULT"
-	attrDuration          = "DURATION"
-	attrEndDate           = "END-DATE"
-	attrEndOnNext         = "END-ON-NEXT"
-	attrForced            = "FORCED"
-	attrFrameRate         = "FRAME-RATE"
-	attrGroupID           = "GROUP-ID"
-	attrHDCPLevel         = "HDCP-LEVEL"
-	attrID                = "ID"
-	attrInstreamID        = "INSTREAM-ID"
-	attrIV                = "IV"
-	attrKeyFormat         = "KEYFORMAT"
-	attrKeyFormatVersions = "KEYFORMATVERSIONS"
-	attrLanguage          = "LANGUAGE"
-	attrMethod            = "METHOD"
-	attrName              = "NAME"
-	attrPlannedDuration   = "PLANNED-DURATION"
-	attrPrecise           = "PRECISE"
-	attrProgramID         = "PROGRAM-ID"
-	attrResolution        = "RESOLUTION"
-	attrSCTE35Command     = "SCTE35-CMD"
-	attrSCTE35In          = "SCTE35-IN"
-	attrSCTE35Out         = "SCTE35-OUT"
-	attrStartDate         = "START-DATE"
-	attrSubtitles         = "SUBTITLES"
-	attrTimeOffset        = "TIME-OFFSET"
-	attrType              = "TYPE"
-	attrURI               = "URI"
-	attrValue             = "VALUE"
-	attrVideo             = "VIDEO"
+	attrAllowedCPC                = "ALLOWED-CPC"
+	attrAssocLanguage             = "ASSOC-LANGUAGE"
+	attrAudio                     = "AUDIO"
+	attrAutoselect                = "AUTOSELECT"
+	attrAverageBandwidth          = "AVERAGE-BANDWIDTH"
+	attrBandwidth                 = "BANDWIDTH"
+	attrByteRange                 = "BYTERANGE"
+	attrByteRangeLength           = "BYTERANGE-LENGTH"
+	attrByteRangeStart            = "BYTERANGE-START"
+	attrCanBlockReload            = "CAN-BLOCK-RELOAD"
+	attrCanSkipDateRanges         = "CAN-SKIP-DATERANGES"
+	attrCanSkipUntil              = "CAN-SKIP-UNTIL"
+	attrClass                     = "CLASS"
+	attrCharacteristics           = "CHARACTERISTICS"
+	attrChannels                  = "CHANNELS"
+	attrClosedCaptions            = "CLOSED-CAPTIONS"
+	attrCodecs                    = "CODECS"
+	attrDataID                    = "DATA-ID"
+	attrDefault                   = "DEFAULT"
+	attrDuration                  = "DURATION"
+	attrEndDate                   = "END-DATE"
+	attrEndOnNext                 = "END-ON-NEXT"
+	attrForced                    = "FORCED"
+	attrFrameRate                 = "FRAME-RATE"
+	attrGap                       = "GAP"
+	attrGroupID                   = "GROUP-ID"
+	attrHDCPLevel                 = "HDCP-LEVEL"
+	attrHoldBack                  = "HOLD-BACK"
+	attrID                        = "ID"
+	attrImport                    = "IMPORT"
+	attrIndependent               = "INDEPENDENT"
+	attrInstreamID                = "INSTREAM-ID"
+	attrIV                        = "IV"
+	attrKeyFormat                 = "KEYFORMAT"
+	attrKeyFormatVersions         = "KEYFORMATVERSIONS"
+	attrLanguage                  = "LANGUAGE"
+	attrLastMSN                   = "LAST-MSN"
+	attrLastPart                  = "LAST-PART"
+	attrMethod                    = "METHOD"
+	attrName                      = "NAME"
+	attrPartHoldBack              = "PART-HOLD-BACK"
+	attrPartTarget                = "PART-TARGET"
+	attrPathwayID                 = "PATHWAY-ID"
+	attrPlannedDuration           = "PLANNED-DURATION"
+	attrPrecise                   = "PRECISE"
+	attrProgramID                 = "PROGRAM-ID"
+	attrQueryParam                = "QUERYPARAM"
+	attrRecentlyRemovedDateRanges = "RECENTLY-REMOVED-DATERANGES"
+	attrReqVideoLayout            = "REQ-VIDEO-LAYOUT"
+	attrResolution                = "RESOLUTION"
+	attrScore                     = "SCORE"
+	attrSCTE35Command             = "SCTE35-CMD"
+	attrSCTE35In                  = "SCTE35-IN"
+	attrSCTE35Out                 = "SCTE35-OUT"
+	attrServerURI                 = "SERVER-URI"
+	attrSkippedSegments           = "SKIPPED-SEGMENTS"
+	attrStableRenditionID         = "STABLE-RENDITION-ID"
+	attrStableVariantID           = "STABLE-VARIANT-ID"
+	attrStartDate                 = "START-DATE"
+	attrSupplementalCodecs        = "SUPPLEMENTAL-CODECS"
+	attrSubtitles                 = "SUBTITLES"
+	attrTimeOffset                = "TIME-OFFSET"
+	attrType                      = "TYPE"
+	attrURI                       = "URI"
+	attrValue                     = "VALUE"
+	attrVideo                     = "VIDEO"
+	attrVideoRange                = "VIDEO-RANGE"
 )
+
+// attributeOrders lists the attributes of each tag in the order in which they
+// are conventionally written, which follows the order in which the
+// specification defines them, except that the attributes that identify an
+// EXT-X-MEDIA rendition come first.
+var attributeOrders = map[string]attributeOrder{
+	keyTag:             {attrMethod, attrURI, attrIV, attrKeyFormat, attrKeyFormatVersions},
+	mapTag:             {attrURI, attrByteRange},
+	daterangeTag:       {attrID, attrClass, attrStartDate, attrEndDate, attrDuration, attrPlannedDuration, attrSCTE35Command, attrSCTE35Out, attrSCTE35In, attrEndOnNext},
+	partTag:            {attrDuration, attrURI, attrIndependent, attrByteRange, attrGap},
+	serverControlTag:   {attrCanSkipUntil, attrCanSkipDateRanges, attrHoldBack, attrPartHoldBack, attrCanBlockReload},
+	preloadHintTag:     {attrType, attrURI, attrByteRangeStart, attrByteRangeLength},
+	renditionReportTag: {attrURI, attrLastMSN, attrLastPart},
+	skipTag:            {attrSkippedSegments, attrRecentlyRemovedDateRanges},
+	mediaTag:           {attrType, attrGroupID, attrName, attrLanguage, attrAssocLanguage, attrStableRenditionID, attrDefault, attrAutoselect, attrForced, attrInstreamID, attrCharacteristics, attrChannels, attrURI},
+	streamInfTag:       {attrBandwidth, attrAverageBandwidth, attrScore, attrCodecs, attrSupplementalCodecs, attrResolution, attrFrameRate, attrHDCPLevel, attrAllowedCPC, attrVideoRange, attrReqVideoLayout, attrStableVariantID, attrAudio, attrVideo, attrSubtitles, attrClosedCaptions, attrPathwayID},
+	iFrameStreamInfTag: {attrBandwidth, attrAverageBandwidth, attrScore, attrCodecs, attrSupplementalCodecs, attrResolution, attrHDCPLevel, attrAllowedCPC, attrVideoRange, attrReqVideoLayout, attrStableVariantID, attrVideo, attrPathwayID, attrURI},
+	sessionDataTag:     {attrDataID, attrValue, attrURI, attrLanguage},
+	sessionKeyTag:      {attrMethod, attrURI, attrIV, attrKeyFormat, attrKeyFormatVersions},
+	contentSteeringTag: {attrServerURI, attrPathwayID},
+	startTag:           {attrTimeOffset, attrPrecise},
+	defineTag:          {attrName, attrValue, attrImport, attrQueryParam},
+}
@@ -0,0 +1,270 @@
+package m3u8
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"io"
+)
+
+// KeyFetcher obtains the keys of Media Segments.
+type KeyFetcher interface {
+	// FetchKey returns the key that is identified by uri, the URI value of a
+	// Key, which may need to be resolved against the URI of the playlist.
+	FetchKey(ctx context.Context, uri string) ([]byte, error)
+}
+
+// KeyFetcherFunc is an adapter to allow the use of ordinary functions as
+// KeyFetchers.
+type KeyFetcherFunc func(ctx context.Context, uri string) ([]byte, error)
+
+func (f KeyFetcherFunc) FetchKey(ctx context.Context, uri string) ([]byte, error) {
+	return f(ctx, uri)
+}
+
+// NewDecryptingReader returns a reader of the plaintext of a Media Segment
+// that was encrypted with the AES128 method, which is AES-128 in CBC mode
+// with PKCS7 padding, and that is read from r.
+//
+// The key must be 16 bytes long.
+//
+// See https://tools.ietf.org/html/rfc8216#section-4.3.2.4.
+func NewDecryptingReader(r io.Reader, key []byte, iv [16]byte) (io.Reader, error) {
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingReader{
+		r:    r,
+		mode: cipher.NewCBCDecrypter(block, iv[:]),
+	}, nil
+}
+
+type decryptingReader struct {
+	r    io.Reader
+	mode cipher.BlockMode
+
+	// buf holds the ciphertext that is not yet decrypted, which always
+	// includes the last block until the end of r since it contains the
+	// padding
+	buf   []byte
+	chunk [4096]byte
+
+	out []byte
+	err error
+}
+
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		d.fill()
+	}
+
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+
+	return n, nil
+}
+
+func (d *decryptingReader) fill() {
+	n, err := d.r.Read(d.chunk[:])
+	d.buf = append(d.buf, d.chunk[:n]...)
+
+	if err == io.EOF {
+		if len(d.buf) == 0 || len(d.buf)%aes.BlockSize != 0 {
+			d.err = ErrBadPadding
+			return
+		}
+
+		d.mode.CryptBlocks(d.buf, d.buf)
+
+		padding := int(d.buf[len(d.buf)-1])
+		if padding == 0 || padding > aes.BlockSize {
+			d.err = ErrBadPadding
+			return
+		}
+
+		for _, b := range d.buf[len(d.buf)-padding:] {
+			if int(b) != padding {
+				d.err = ErrBadPadding
+				return
+			}
+		}
+
+		d.out, d.buf = d.buf[:len(d.buf)-padding], nil
+		d.err = io.EOF
+
+		return
+	}
+
+	if err != nil {
+		d.err = err
+		return
+	}
+
+	if n := (len(d.buf) - 1) / aes.BlockSize * aes.BlockSize; n > 0 {
+		d.out = make([]byte, n)
+		d.mode.CryptBlocks(d.out, d.buf[:n])
+		d.buf = append(d.buf[:0], d.buf[n:]...)
+	}
+}
+
+// NewEncryptingWriter returns a writer that encrypts a Media Segment with the
+// AES128 method, which is AES-128 in CBC mode with PKCS7 padding, and writes
+// the ciphertext to w.
+//
+// The last block is only written once the returned writer is closed, which
+// does not close w. The key must be 16 bytes long.
+//
+// See https://tools.ietf.org/html/rfc8216#section-4.3.2.4.
+func NewEncryptingWriter(w io.Writer, key []byte, iv [16]byte) (io.WriteCloser, error) {
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptingWriter{
+		w:    w,
+		mode: cipher.NewCBCEncrypter(block, iv[:]),
+	}, nil
+}
+
+type encryptingWriter struct {
+	w    io.Writer
+	mode cipher.BlockMode
+
+	// buf holds the plaintext of the incomplete last block
+	buf    []byte
+	closed bool
+}
+
+func (e *encryptingWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, &Error{"write to a closed writer"}
+	}
+
+	e.buf = append(e.buf, p...)
+
+	if n := len(e.buf) / aes.BlockSize * aes.BlockSize; n > 0 {
+		out := make([]byte, n)
+		e.mode.CryptBlocks(out, e.buf[:n])
+		e.buf = append(e.buf[:0], e.buf[n:]...)
+
+		if _, err := e.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (e *encryptingWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+
+	e.closed = true
+
+	padding := aes.BlockSize - len(e.buf)
+	for i := 0; i < padding; i++ {
+		e.buf = append(e.buf, byte(padding))
+	}
+
+	e.mode.CryptBlocks(e.buf, e.buf)
+
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+func newCipher(key []byte) (cipher.Block, error) {
+	if len(key) != aes.BlockSize {
+		return nil, ErrBadKeyLength
+	}
+
+	return aes.NewCipher(key)
+}
+
+// segmentKey returns the key and IV with which the Media Segment at index i
+// of Segments is encrypted, or a nil key if it is not encrypted. It fails if
+// the segment is encrypted, but not with a key of the implicit key format and
+// the AES128 method.
+func (p *MediaPlaylist) segmentKey(ctx context.Context, i int, f KeyFetcher) ([]byte, [16]byte, error) {
+	var iv [16]byte
+
+	keys := p.EffectiveKeys(i)
+	if len(keys) == 0 {
+		return nil, iv, nil
+	}
+
+	var key *Key
+	for _, k := range keys {
+		if k.keyFormat() == identityKeyFormat {
+			key = k
+		}
+	}
+
+	if key == nil {
+		return nil, iv, &Error{"cannot decrypt segments without a key with the " + identityKeyFormat + " key format"}
+	}
+
+	if key.Method != AES128 {
+		return nil, iv, &Error{"cannot decrypt segments with method " + key.method()}
+	}
+
+	iv, _ = p.effectiveIV(i, keys, identityKeyFormat)
+
+	b, err := f.FetchKey(ctx, key.URI)
+	return b, iv, err
+}
+
+// DecryptSegment returns a reader of the plaintext of the Media Segment at
+// index i of Segments, which is read from r. The segment is decrypted with the
+// effective key with the implicit key format, as returned by EffectiveKeys,
+// which is obtained from f, and the IV returned by EffectiveIV.
+//
+// If the segment is not encrypted, r is returned as is. An error is returned
+// if the segment can only be decrypted with keys of other key formats.
+func (p *MediaPlaylist) DecryptSegment(ctx context.Context, i int, r io.Reader, f KeyFetcher) (io.Reader, error) {
+	key, iv, err := p.segmentKey(ctx, i, f)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return r, nil
+	}
+
+	return NewDecryptingReader(r, key, iv)
+}
+
+// EncryptSegment returns a writer that encrypts the Media Segment at index i
+// of Segments and writes the ciphertext to w, such that it can be decrypted
+// with DecryptSegment.
+//
+// If the segment is not encrypted, the returned writer writes to w as is. An
+// error is returned if the segment can only be encrypted with keys of other
+// key formats.
+func (p *MediaPlaylist) EncryptSegment(ctx context.Context, i int, w io.Writer, f KeyFetcher) (io.WriteCloser, error) {
+	key, iv, err := p.segmentKey(ctx, i, f)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return nopWriteCloser{w}, nil
+	}
+
+	return NewEncryptingWriter(w, key, iv)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
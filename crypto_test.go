@@ -0,0 +1,115 @@
+package m3u8_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ssttevee/m3u8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentEncryption(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	iv := [16]byte{15: 1}
+
+	for _, size := range []int{0, 1, 15, 16, 17, 10000} {
+		plaintext := bytes.Repeat([]byte{'x'}, size)
+
+		var buf bytes.Buffer
+		w, err := m3u8.NewEncryptingWriter(&buf, key, iv)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		_, err = w.Write(plaintext)
+		assert.Nil(t, err)
+		assert.Nil(t, w.Close())
+
+		if !assert.Equal(t, (size/aes.BlockSize+1)*aes.BlockSize, buf.Len(), "should pad %d bytes", size) {
+			continue
+		}
+
+		// the ciphertext must be compatible with other implementations
+		block, _ := aes.NewCipher(key)
+		decrypted := make([]byte, buf.Len())
+		cipher.NewCBCDecrypter(block, iv[:]).CryptBlocks(decrypted, buf.Bytes())
+		assert.Equal(t, plaintext, decrypted[:size])
+
+		r, err := m3u8.NewDecryptingReader(iotest.OneByteReader(&buf), key, iv)
+		if assert.Nil(t, err) {
+			got, err := ioutil.ReadAll(r)
+			assert.Nil(t, err)
+			assert.Equal(t, plaintext, got)
+		}
+	}
+
+	_, err := m3u8.NewDecryptingReader(nil, key[:8], iv)
+	assert.Equal(t, m3u8.ErrBadKeyLength, err)
+
+	r, _ := m3u8.NewDecryptingReader(bytes.NewReader(make([]byte, 17)), key, iv)
+	_, err = ioutil.ReadAll(r)
+	assert.Equal(t, m3u8.ErrBadPadding, err)
+}
+
+func TestDecryptSegment(t *testing.T) {
+	const data = "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:7\n#EXTINF:4,\n0.ts\n#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n#EXTINF:4,\n1.ts\n"
+
+	plist, err := m3u8.DecodePlaylist([]byte(data))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	p := plist.(*m3u8.MediaPlaylist)
+
+	key := []byte("0123456789abcdef")
+	fetcher := m3u8.KeyFetcherFunc(func(ctx context.Context, uri string) ([]byte, error) {
+		assert.Equal(t, "a.key", uri)
+		return key, nil
+	})
+
+	var buf bytes.Buffer
+	w, err := p.EncryptSegment(context.Background(), 1, &buf, fetcher)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	w.Write([]byte("segment"))
+	w.Close()
+
+	// the iv is the media sequence number of the segment
+	block, _ := aes.NewCipher(key)
+	decrypted := make([]byte, buf.Len())
+	cipher.NewCBCDecrypter(block, (&[16]byte{15: 8})[:]).CryptBlocks(decrypted, buf.Bytes())
+	assert.Equal(t, []byte("segment"), decrypted[:7])
+
+	r, err := p.DecryptSegment(context.Background(), 1, &buf, fetcher)
+	if assert.Nil(t, err) {
+		got, _ := ioutil.ReadAll(r)
+		assert.Equal(t, "segment", string(got))
+	}
+
+	plain := bytes.NewReader(nil)
+	r, err = p.DecryptSegment(context.Background(), 0, plain, fetcher)
+	assert.Nil(t, err)
+	assert.Equal(t, plain, r)
+
+	// segments that are only encrypted with keys of other key formats are
+	// not passed through
+	plist, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-VERSION:5\n#EXT-X-TARGETDURATION:4\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"skd://a\",KEYFORMAT=\"com.apple.streamingkeydelivery\"\n#EXTINF:4,\n0.ts\n"))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	p = plist.(*m3u8.MediaPlaylist)
+
+	_, err = p.DecryptSegment(context.Background(), 0, plain, fetcher)
+	assert.NotNil(t, err, "should not decrypt segments without an identity key")
+
+	_, err = p.EncryptSegment(context.Background(), 0, &buf, fetcher)
+	assert.NotNil(t, err, "should not encrypt segments without an identity key")
+}
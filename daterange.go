@@ -194,6 +194,21 @@ func parseDateRange(meta string) (*DateRange, error) {
 	return &dr, nil
 }
 
+// ParseStartDate returns the StartDate value as a time.Time.
+func (r *DateRange) ParseStartDate() (time.Time, error) {
+	return parseDate(r.StartDate)
+}
+
+// ParseEndDate returns the EndDate value as a time.Time, or the zero time if
+// it is empty.
+func (r *DateRange) ParseEndDate() (time.Time, error) {
+	if r.EndDate == "" {
+		return time.Time{}, nil
+	}
+
+	return parseDate(r.EndDate)
+}
+
 func (r DateRange) attrs() (attributes, error) {
 	attrs := attributes{
 		attrID:        r.ID,
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"math"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -11,8 +13,45 @@ import (
 )
 
 type Decoder struct {
-	r      io.Reader
+	r io.Reader
+
+	// Strict rejects unknown tags and unknown encryption methods. Otherwise,
+	// unknown tags are ignored and keys with an unknown encryption method
+	// are decoded with the UnknownEncryption method. It is enabled by
+	// NewDecoder.
 	Strict bool
+
+	// Variables are the variables defined by the Master Playlist that refers
+	// to the Media Playlist being decoded. They are used to resolve
+	// EXT-X-DEFINE tags with an IMPORT attribute.
+	Variables map[string]string
+
+	// Query holds the query parameters of the URI from which the playlist was
+	// obtained. They are used to resolve EXT-X-DEFINE tags with a QUERYPARAM
+	// attribute.
+	Query url.Values
+
+	// KeepVariableReferences disables variable substitution so that variable
+	// references are preserved, and encoded, in their unexpanded form.
+	// References to undefined variables are still rejected.
+	KeepVariableReferences bool
+
+	// Lossless retains comments and unknown tags as RawLines of the element
+	// they appear in, so that they are re-emitted at their original positions
+	// when the playlist is encoded. Unknown tags are not rejected in lossless
+	// mode, regardless of Strict. The order of the attributes of each tag is
+	// also retained in the Layout of the playlist; see
+	// Encoder.PreserveAttributeOrder.
+	Lossless bool
+
+	// AllErrors continues decoding past recoverable errors, such as invalid
+	// tags or tags that require a higher compatibility version number. The
+	// tags that cannot be parsed are skipped, and Decode returns the
+	// resulting playlist along with an ErrorList of every error that was
+	// found, which can be inspected with errors.Is and errors.As.
+	AllErrors bool
+
+	tags tagHandlers
 }
 
 func NewDecoder(r io.Reader) *Decoder {
@@ -22,6 +61,17 @@ func NewDecoder(r io.Reader) *Decoder {
 	}
 }
 
+// RegisterTag registers the handler for the custom tag with the given name,
+// such as "#EXT-X-CUE-OUT". The Parse function of the handler is used to
+// decode the tag into a CustomTag, which is attached to the next Media Segment
+// or to the Playlist, depending on the Scope of the handler.
+//
+// Tags that are defined by the specification are always handled by the
+// Decoder. RegisterTag panics if the name does not start with "#EXT".
+func (d *Decoder) RegisterTag(name string, h *TagHandler) {
+	d.tags.register(name, h)
+}
+
 func (d *Decoder) Decode() (Playlist, error) {
 	scanner := bufio.NewScanner(d.r)
 	if !scanner.Scan() {
@@ -32,12 +82,7 @@ func (d *Decoder) Decode() (Playlist, error) {
 		return nil, ErrNoHeader
 	}
 
-	p, err := decode(scanner, d.Strict)
-	if err != nil {
-		return nil, err
-	}
-
-	return p, nil
+	return decode(scanner, d)
 }
 
 func DecodePlaylist(data []byte) (Playlist, error) {
@@ -54,12 +99,29 @@ func (u uri) line() string {
 	return string(u)
 }
 
+// raw is a comment or an unknown tag that is retained in lossless mode.
+type raw string
+
+func (r raw) line() string {
+	return string(r)
+}
+
 type split struct {
 	num  int
 	tag  string
 	meta string
 }
 
+// Line returns the number of the line that contains the tag, where the
+// header tag is on line 1. It returns 0 if the line is unknown.
+func (s *split) Line() int {
+	if s == nil {
+		return 0
+	}
+
+	return s.num
+}
+
 func (s *split) line() string {
 	if s.meta == "" {
 		return s.tag
@@ -69,7 +131,9 @@ func (s *split) line() string {
 }
 
 func secondsToDuration(s float64) time.Duration {
-	return time.Duration(s * float64(time.Second))
+	// round to the nearest nanosecond so that decimal values like 9.009 are
+	// not truncated to 9.008999999
+	return time.Duration(math.Round(s * float64(time.Second)))
 }
 
 func parseDuration(str string) (time.Duration, error) {
@@ -83,6 +147,33 @@ func parseDuration(str string) (time.Duration, error) {
 
 var rxISO8601 = regexp.MustCompile("^[+-]?\\d{4,}(?:-?(?:\\d{2}(?:-?\\d{2})?|W\\d{2}(?:-?\\d)?|\\d{3}))?(?:T\\d{2}(?::?\\d{2}(?::?\\d{2}(?:\\.\\d+)?)?)?(?:Z|[+-]\\d{2}(?::?\\d{2})?)?)?$")
 
+// dateLayouts are the variants of the ISO/IEC 8601:2004 format that are
+// emitted by encoders, in both the extended and basic formats. Fractional
+// seconds are always accepted after the seconds.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05Z07",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04Z0700",
+	"2006-01-02T15:04Z07",
+	"20060102T150405Z0700",
+	"20060102T150405Z07:00",
+	"20060102T150405Z07",
+}
+
+// parseDate parses a date in the ISO/IEC 8601:2004 format that is used by the
+// specification, which must include a time zone.
+func parseDate(str string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, &Error{msg: "invalid date format"}
+}
+
 func validateDate(str string) error {
 	if rxISO8601.MatchString(str) {
 		return nil
@@ -93,11 +184,22 @@ func validateDate(str string) error {
 
 // decode determines the playlist type, parses common tags, and buffers
 // important lines for further processing.
-func decode(scanner *bufio.Scanner, strict bool) (Playlist, error) {
+//
+// If the decoder continues past recoverable errors, the playlist is returned
+// along with the errors that were collected.
+func decode(scanner *bufio.Scanner, d *Decoder) (Playlist, error) {
 	var pType Type
 	var lines []line
 
-	var base GenericPlaylist
+	errs := &errorCollector{enabled: d.AllErrors}
+
+	base := GenericPlaylist{Layout: newLayout(d.Lossless)}
+
+	// variables and the first lines that define them; variable references
+	// are only processed in playlists that define variables or declare a
+	// compatibility version that supports them
+	vars := map[string]string{}
+	var define, importDefine, queryParamDefine *split
 
 	for lineNumber := 2; scanner.Scan(); lineNumber++ {
 		line := scanner.Text()
@@ -108,16 +210,31 @@ func decode(scanner *bufio.Scanner, strict bool) (Playlist, error) {
 
 		if line[0] != '#' {
 			// this is a url line
+			if define != nil || base.Version >= 8 {
+				expanded, err := substituteVariables(line, vars)
+				if err != nil {
+					if err := errs.handle(isew(&split{num: lineNumber, tag: line}, err)); err != nil {
+						return nil, err
+					}
+				} else if !d.KeepVariableReferences {
+					line = expanded
+				}
+			}
+
 			lines = append(lines, uri(line))
 			continue
 		}
 
-		if line[:4] != tagPrefix {
+		if !strings.HasPrefix(line, tagPrefix) {
 			// ignore comments
+			if d.Lossless {
+				lines = append(lines, raw(line))
+			}
+
 			continue
 		}
 
-		var s split
+		s := split{num: lineNumber}
 		if colon := strings.IndexRune(line, ':'); colon >= 0 {
 			s.tag = line[:colon]
 			s.meta = line[colon+1:]
@@ -125,21 +242,37 @@ func decode(scanner *bufio.Scanner, strict bool) (Playlist, error) {
 			s.tag = line
 		}
 
+		if (define != nil || base.Version >= 8) && s.tag != defineTag && s.tag != infTag {
+			expanded, err := substituteQuotedStrings(s.meta, vars)
+			if err != nil {
+				if err := errs.handle(isew(&s, err)); err != nil {
+					return nil, err
+				}
+			} else if !d.KeepVariableReferences {
+				s.meta = expanded
+			}
+		}
+
 		switch s.tag {
 		case versionTag:
+			// the version line is kept so that the positions of custom tags
+			// and raw lines can be determined
 			num, err := strconv.ParseInt(s.meta, 0, 64)
 			if err != nil {
-				return nil, ErrBadVersionNumber
+				if err := errs.handle(isew(&s, ErrBadVersionNumber)); err != nil {
+					return nil, err
+				}
+
+				break
 			}
 
 			base.Version = int(num)
-			continue
 
-		case infTag, byterangeTag, discontinuityTag, keyTag, mapTag, programDateTimeTag, daterangeTag:
+		case infTag, byterangeTag, discontinuityTag, keyTag, mapTag, programDateTimeTag, daterangeTag, partTag, gapTag, bitrateTag:
 			// media segment tags
 			fallthrough
 
-		case targetdurationTag, mediaSequenceTag, discontinuitySequenceTag, endlistTag, playlistTypeTag, iFramesOnlyTag:
+		case targetdurationTag, mediaSequenceTag, discontinuitySequenceTag, endlistTag, playlistTypeTag, iFramesOnlyTag, partInfTag, serverControlTag, preloadHintTag, renditionReportTag, skipTag:
 			// media playlist tags
 			if pType == 0 {
 				pType = Media
@@ -149,7 +282,7 @@ func decode(scanner *bufio.Scanner, strict bool) (Playlist, error) {
 				return nil, ErrMixedTags
 			}
 
-		case mediaTag, streamInfTag, iFrameStreamInfTag, sessionDataTag, sessionKeyTag:
+		case mediaTag, streamInfTag, iFrameStreamInfTag, sessionDataTag, sessionKeyTag, contentSteeringTag:
 			// master playlist tags
 			if pType == 0 {
 				pType = Master
@@ -162,59 +295,135 @@ func decode(scanner *bufio.Scanner, strict bool) (Playlist, error) {
 		case independentSegmentsTag:
 			base.IndependentSegments = true
 
-		case startTag:
-			attrs, err := parseAttributeList(s.meta)
+		case defineTag:
+			def, err := parseDefinition(s.meta)
 			if err != nil {
-				return nil, isew(&s, err)
+				if err := errs.handle(isew(&s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
-			var start Start
-			timeOffset, err := attrs.float(attrTimeOffset)
-			if err != nil {
-				return nil, isew(&s, err)
+			if _, ok := vars[def.Name]; ok {
+				if err := errs.handle(ise(&s, "variable is already defined")); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
-			start.TimeOffset = secondsToDuration(timeOffset)
+			var ok bool
+			switch {
+			case def.Import:
+				def.Value, ok = d.Variables[def.Name]
+				if importDefine == nil {
+					importDefine = &s
+				}
+
+			case def.QueryParam:
+				_, ok = d.Query[def.Name]
+				def.Value = d.Query.Get(def.Name)
+				if queryParamDefine == nil {
+					queryParamDefine = &s
+				}
 
-			precise, err := attrs.enum(attrPrecise)
-			if missing := isMissingAttr(err); err != nil && !missing {
-				return nil, isew(&s, err)
-			} else if !missing {
-				switch precise {
-				case "YES":
-					start.Precise = true
-				case "NO":
-				default:
-					return nil, &invalidAttributeValueError{attrPrecise}
+			default:
+				ok = true
+			}
+
+			if !ok && !d.KeepVariableReferences {
+				if err := errs.handle(ise(&s, "failed to resolve variable value")); err != nil {
+					return nil, err
 				}
 			}
 
+			base.Layout.record(def, &s)
+			vars[def.Name] = def.Value
+			base.Definitions = append(base.Definitions, def)
+			if define == nil {
+				define = &s
+			}
+
+		case startTag:
+			start, err := parseStart(s.meta)
+			if err != nil {
+				if err := errs.handle(isew(&s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			base.Layout.record(start, &s)
+			base.Start = start
+
 		default:
-			if strict {
-				return nil, (*UnexpectedTagError)(&s)
+			if _, ok := d.tags[s.tag]; ok {
+				c, err := d.tags.parse(&s)
+				if err != nil {
+					if err := errs.handle(isew(&s, err)); err != nil {
+						return nil, err
+					}
+
+					continue
+				}
+
+				lines = append(lines, c)
+				continue
+			}
+
+			if d.Lossless {
+				lines = append(lines, raw(line))
+				continue
+			}
+
+			if d.Strict {
+				if err := errs.handle((*UnexpectedTagError)(&s)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 		}
 
-		s.num = lineNumber
 		lines = append(lines, &s)
 	}
 
+	if define != nil && base.Version < 8 {
+		if err := errs.handle(&CompatibilityVersionError{define, 8}); err != nil {
+			return nil, err
+		}
+	}
+
+	if queryParamDefine != nil && base.Version < 11 {
+		if err := errs.handle(&CompatibilityVersionError{queryParamDefine, 11}); err != nil {
+			return nil, err
+		}
+	}
+
+	if importDefine != nil && pType == Master {
+		if err := errs.handle(ise(importDefine, "imported variables are not allowed in a master playlist")); err != nil {
+			return nil, err
+		}
+	}
+
 	switch pType {
 	case Media:
-		p, err := parseMediaPlaylist(&base, lines)
+		p, err := parseMediaPlaylist(&base, lines, d.Strict, errs)
 		if err != nil {
 			return nil, err
 		}
 
-		return p, nil
+		return p, errs.err()
 
 	case Master:
-		p, err := parseMasterPlaylist(&base, lines)
+		p, err := parseMasterPlaylist(&base, lines, d.Strict, errs)
 		if err != nil {
 			return nil, err
 		}
 
-		return p, nil
+		return p, errs.err()
 
 	default:
 		return nil, ErrUnknownType
@@ -1,6 +1,9 @@
 package m3u8_test
 
 import (
+	"bytes"
+	"errors"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -50,6 +53,18 @@ func TestDecodePlaylist(t *testing.T) {
 		}
 	})
 
+	t.Run("media playlist with inexact decimal durations", func(t *testing.T) {
+		// 2.002 seconds is 2001999999.9999998 nanoseconds as a float64
+		plist, err := m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:3\n#EXTINF:2.002,\nfirst.ts\n"))
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		if mplist := plist.(*m3u8.MediaPlaylist); assert.Len(t, mplist.Segments, 1) {
+			assert.Equal(t, 2*time.Second+2*time.Millisecond, mplist.Segments[0].Duration)
+		}
+	})
+
 	t.Run("live media playlist using https", func(t *testing.T) {
 		const data = "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:8\n#EXT-X-MEDIA-SEQUENCE:2680\n\n#EXTINF:7.975,\nhttps://priv.example.com/fileSequence2680.ts\n#EXTINF:7.941,\nhttps://priv.example.com/fileSequence2681.ts\n#EXTINF:7.975,\nhttps://priv.example.com/fileSequence2682.ts\n"
 		plist, err := m3u8.DecodePlaylist([]byte(data))
@@ -111,14 +126,14 @@ func TestDecodePlaylist(t *testing.T) {
 				}
 			}
 
-			if assert.NotNil(t, mplist.Segments[0].Key) {
-				assert.Equal(t, m3u8.AES128, mplist.Segments[0].Key.Method)
-				assert.Equal(t, "https://priv.example.com/key.php?r=52", mplist.Segments[0].Key.URI)
+			if assert.Len(t, mplist.Segments[0].Keys, 1) {
+				assert.Equal(t, m3u8.AES128, mplist.Segments[0].Keys[0].Method)
+				assert.Equal(t, "https://priv.example.com/key.php?r=52", mplist.Segments[0].Keys[0].URI)
 			}
 
-			if assert.NotNil(t, mplist.Segments[3].Key) {
-				assert.Equal(t, m3u8.AES128, mplist.Segments[3].Key.Method)
-				assert.Equal(t, "https://priv.example.com/key.php?r=53", mplist.Segments[3].Key.URI)
+			if assert.Len(t, mplist.Segments[3].Keys, 1) {
+				assert.Equal(t, m3u8.AES128, mplist.Segments[3].Keys[0].Method)
+				assert.Equal(t, "https://priv.example.com/key.php?r=53", mplist.Segments[3].Keys[0].URI)
 			}
 		}
 	})
@@ -467,4 +482,496 @@ func TestDecodePlaylist(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("low-latency media playlist with partial segments", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-VERSION:6\n#EXT-X-PART-INF:PART-TARGET=1.002\n#EXT-X-MEDIA-SEQUENCE:266\n#EXTINF:4.00008,\nfileSequence266.mp4\n#EXT-X-PART:DURATION=1.00001,INDEPENDENT=YES,URI=\"filePart267.0.mp4\"\n#EXT-X-PART:DURATION=1.00001,URI=\"filePart267.1.mp4\"\n#EXTINF:2.00002,\nfileSequence267.mp4\n#EXT-X-PART:DURATION=1.00001,INDEPENDENT=YES,URI=\"filePart268.0.mp4\",BYTERANGE=\"2000@0\"\n#EXT-X-PART:DURATION=1.00001,URI=\"filePart268.0.mp4\",BYTERANGE=\"1500\"\n"
+
+		plist, err := m3u8.DecodePlaylist([]byte(data))
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		if !assert.Equal(t, m3u8.Media, plist.Type(), "should be a media playlist") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MediaPlaylist)
+
+		assert.Equal(t, 1*time.Second+2*time.Millisecond, mplist.PartTarget)
+		assert.True(t, mplist.Live)
+
+		if assert.Len(t, mplist.Segments, 2) {
+			assert.Empty(t, mplist.Segments[0].Parts)
+
+			if assert.Len(t, mplist.Segments[1].Parts, 2) {
+				assert.Equal(t, "filePart267.0.mp4", mplist.Segments[1].Parts[0].URI)
+				assert.Equal(t, 1*time.Second+10*time.Microsecond, mplist.Segments[1].Parts[0].Duration)
+				assert.True(t, mplist.Segments[1].Parts[0].Independent)
+				assert.Equal(t, "filePart267.1.mp4", mplist.Segments[1].Parts[1].URI)
+				assert.False(t, mplist.Segments[1].Parts[1].Independent)
+			}
+		}
+
+		if assert.Len(t, mplist.Parts, 2) {
+			if assert.NotNil(t, mplist.Parts[0].ByteRange) {
+				assert.Equal(t, int64(2000), mplist.Parts[0].ByteRange.Length)
+				assert.Equal(t, int64(0), mplist.Parts[0].ByteRange.Start)
+			}
+
+			if assert.NotNil(t, mplist.Parts[1].ByteRange) {
+				assert.Equal(t, int64(1500), mplist.Parts[1].ByteRange.Length)
+				assert.Equal(t, int64(-1), mplist.Parts[1].ByteRange.Start)
+			}
+		}
+	})
+
+	t.Run("media playlist with server control", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-VERSION:6\n#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,CAN-SKIP-UNTIL=24,HOLD-BACK=12,PART-HOLD-BACK=3.012\n#EXT-X-PART-INF:PART-TARGET=1.004\n#EXT-X-MEDIA-SEQUENCE:266\n#EXTINF:4.00008,\nfileSequence266.mp4\n"
+
+		plist, err := m3u8.DecodePlaylist([]byte(data))
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MediaPlaylist)
+
+		if assert.NotNil(t, mplist.ServerControl) {
+			assert.True(t, mplist.ServerControl.CanBlockReload)
+			assert.False(t, mplist.ServerControl.CanSkipDateRanges)
+			assert.Equal(t, 24*time.Second, mplist.ServerControl.CanSkipUntil)
+			assert.Equal(t, 12*time.Second, mplist.ServerControl.HoldBack)
+			assert.Equal(t, 3*time.Second+12*time.Millisecond, mplist.ServerControl.PartHoldBack)
+		}
+	})
+
+	t.Run("media playlist with insufficient hold back", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-VERSION:6\n#EXT-X-SERVER-CONTROL:HOLD-BACK=8\n#EXTINF:4.00008,\nfileSequence266.mp4\n"
+
+		_, err := m3u8.DecodePlaylist([]byte(data))
+		assert.IsType(t, &m3u8.InvalidSyntaxError{}, err)
+	})
+
+	t.Run("low-latency media playlist with preload hints and rendition reports", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-VERSION:6\n#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=3.012\n#EXT-X-PART-INF:PART-TARGET=1.004\n#EXT-X-MEDIA-SEQUENCE:266\n#EXTINF:4.00008,\nfileSequence266.mp4\n#EXT-X-PART:DURATION=1.00001,INDEPENDENT=YES,URI=\"filePart267.0.mp4\"\n#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"filePart267.1.mp4\"\n#EXT-X-RENDITION-REPORT:URI=\"../1M/waitForMSN.php\",LAST-MSN=267,LAST-PART=0\n#EXT-X-RENDITION-REPORT:URI=\"../4M/waitForMSN.php\",LAST-MSN=266\n"
+
+		plist, err := m3u8.DecodePlaylist([]byte(data))
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MediaPlaylist)
+
+		assert.Len(t, mplist.Segments, 1)
+		assert.Len(t, mplist.Parts, 1)
+
+		if assert.Len(t, mplist.PreloadHints, 1) {
+			assert.Equal(t, m3u8.PartHint, mplist.PreloadHints[0].Type)
+			assert.Equal(t, "filePart267.1.mp4", mplist.PreloadHints[0].URI)
+			assert.Nil(t, mplist.PreloadHints.Hint(m3u8.MapHint))
+		}
+
+		if assert.Len(t, mplist.RenditionReports, 2) {
+			assert.Equal(t, "../1M/waitForMSN.php", mplist.RenditionReports[0].URI)
+			assert.Equal(t, uint64(267), mplist.RenditionReports[0].LastMSN)
+			if assert.NotNil(t, mplist.RenditionReports[0].LastPart) {
+				assert.Equal(t, uint64(0), *mplist.RenditionReports[0].LastPart)
+			}
+
+			assert.Equal(t, "../4M/waitForMSN.php", mplist.RenditionReports[1].URI)
+			assert.Equal(t, uint64(266), mplist.RenditionReports[1].LastMSN)
+			assert.Nil(t, mplist.RenditionReports[1].LastPart)
+		}
+	})
+
+	t.Run("media playlist with variable definitions", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-VERSION:11\n#EXT-X-DEFINE:NAME=\"host\",VALUE=\"https://example.com\"\n#EXT-X-DEFINE:IMPORT=\"token\"\n#EXT-X-DEFINE:QUERYPARAM=\"session\"\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=AES-128,URI=\"{$host}/key?t={$token}\"\n#EXTINF:9.009,\n{$host}/first.ts?s={$session}\n#EXT-X-ENDLIST\n"
+
+		decoder := m3u8.NewDecoder(strings.NewReader(data))
+		decoder.Variables = map[string]string{"token": "abc"}
+		decoder.Query = url.Values{"session": {"123"}}
+
+		plist, err := decoder.Decode()
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MediaPlaylist)
+
+		if assert.Len(t, mplist.Definitions, 3) {
+			assert.Equal(t, "host", mplist.Definitions[0].Name)
+			assert.Equal(t, "https://example.com", mplist.Definitions[0].Value)
+			assert.True(t, mplist.Definitions[1].Import)
+			assert.Equal(t, "abc", mplist.Definitions[1].Value)
+			assert.True(t, mplist.Definitions[2].QueryParam)
+			assert.Equal(t, "123", mplist.Definitions[2].Value)
+		}
+
+		if assert.Len(t, mplist.Segments, 1) {
+			assert.Equal(t, "https://example.com/first.ts?s=123", mplist.Segments[0].URI)
+			if assert.Len(t, mplist.Segments[0].Keys, 1) {
+				assert.Equal(t, "https://example.com/key?t=abc", mplist.Segments[0].Keys[0].URI)
+			}
+		}
+
+		decoder = m3u8.NewDecoder(strings.NewReader(data))
+		decoder.KeepVariableReferences = true
+
+		plist, err = decoder.Decode()
+		if !assert.Nil(t, err, "should sucessfully parse without substitution") {
+			t.FailNow()
+		}
+
+		mplist = plist.(*m3u8.MediaPlaylist)
+
+		if assert.Len(t, mplist.Segments, 1) {
+			assert.Equal(t, "{$host}/first.ts?s={$session}", mplist.Segments[0].URI)
+		}
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-VERSION:8\n#EXT-X-TARGETDURATION:10\n#EXTINF:9.009,\n{$host}/first.ts\n"))
+		assert.IsType(t, &m3u8.InvalidSyntaxError{}, err, "should reject undefined variables")
+	})
+
+	t.Run("media playlist with gaps and bitrates", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-VERSION:8\n#EXT-X-TARGETDURATION:10\n#EXT-X-BITRATE:1200\n#EXTINF:9.009,\nfirst.ts\n#EXT-X-GAP\n#EXTINF:9.009,\nsecond.ts\n#EXT-X-BYTERANGE:1000@0\n#EXTINF:9.009,\nthird.ts\n#EXTINF:9.009,\nfourth.ts\n#EXT-X-BITRATE:800\n#EXTINF:9.009,\nfifth.ts\n#EXT-X-ENDLIST\n"
+
+		plist, err := m3u8.DecodePlaylist([]byte(data))
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MediaPlaylist)
+
+		if assert.Len(t, mplist.Segments, 5) {
+			assert.False(t, mplist.Segments[0].Gap)
+			assert.True(t, mplist.Segments[1].Gap)
+
+			assert.Equal(t, uint64(1200), mplist.Segments[0].Bitrate)
+			assert.Equal(t, uint64(0), mplist.Segments[1].Bitrate)
+
+			for i, bitrate := range []uint64{1200, 1200, 0, 1200, 800} {
+				assert.Equal(t, bitrate, mplist.EffectiveBitrate(i))
+			}
+		}
+
+		// gaps do not require a particular compatibility version number
+		plist, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXT-X-GAP\n#EXTINF:9.009,\nfirst.ts\n"))
+		if assert.Nil(t, err) {
+			assert.Equal(t, 3, m3u8.RequiredVersion(plist))
+		}
+	})
+
+	t.Run("media playlist with multiple keys", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-VERSION:5\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"skd://key\",KEYFORMAT=\"com.apple.streamingkeydelivery\",KEYFORMATVERSIONS=\"1\"\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"data:text/plain;base64,AAAA\",KEYFORMAT=\"urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed\",KEYFORMATVERSIONS=\"1\"\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"data:text/plain;charset=UTF-16;base64,BBBB\",KEYFORMAT=\"com.microsoft.playready\",KEYFORMATVERSIONS=\"1\"\n#EXTINF:9.009,\nfirst.ts\n#EXT-X-ENDLIST\n"
+
+		plist, err := m3u8.DecodePlaylist([]byte(data))
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MediaPlaylist)
+
+		if assert.Len(t, mplist.Segments, 1) && assert.Len(t, mplist.Segments[0].Keys, 3) {
+			assert.Equal(t, "com.apple.streamingkeydelivery", mplist.Segments[0].Keys[0].KeyFormat)
+			assert.Equal(t, "urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed", mplist.Segments[0].Keys[1].KeyFormat)
+			assert.Equal(t, "com.microsoft.playready", mplist.Segments[0].Keys[2].KeyFormat)
+			assert.Equal(t, mplist.Segments[0].Keys[2], mplist.Segments[0].Key, "should set the deprecated key to the last key")
+		}
+
+		var buf bytes.Buffer
+		if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(plist), "should successfully encode") {
+			assert.Equal(t, 3, strings.Count(buf.String(), "#EXT-X-KEY:"))
+		}
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n#EXT-X-KEY:METHOD=AES-128,URI=\"b.key\"\n#EXTINF:9,\nfirst.ts\n"))
+		if assert.IsType(t, &m3u8.InvalidSyntaxError{}, err) {
+			assert.Equal(t, 4, err.(*m3u8.InvalidSyntaxError).Line())
+		}
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n#EXT-X-KEY:METHOD=NONE\n#EXTINF:9,\nfirst.ts\n"))
+		assert.IsType(t, &m3u8.InvalidSyntaxError{}, err)
+	})
+
+	t.Run("media playlist with encryption methods", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-VERSION:5\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=SAMPLE-AES-CTR,URI=\"data:text/plain;base64,AAAA\",KEYFORMAT=\"urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed\"\n#EXTINF:9.009,\nfirst.ts\n#EXT-X-KEY:METHOD=SAMPLE-AES-CBC,URI=\"b.key\"\n#EXTINF:9.009,\nsecond.ts\n#EXT-X-ENDLIST\n"
+
+		_, err := m3u8.DecodePlaylist([]byte(data))
+		assert.IsType(t, &m3u8.InvalidSyntaxError{}, err, "should reject unknown methods in strict mode")
+
+		d := m3u8.NewDecoder(strings.NewReader(data))
+		d.Strict = false
+
+		plist, err := d.Decode()
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MediaPlaylist)
+
+		if assert.Len(t, mplist.Segments, 2) {
+			if assert.Len(t, mplist.Segments[0].Keys, 1) {
+				assert.Equal(t, m3u8.SampleAESCTR, mplist.Segments[0].Keys[0].Method)
+				assert.Equal(t, "SAMPLE-AES-CTR", mplist.Segments[0].Keys[0].Method.String())
+			}
+
+			if assert.Len(t, mplist.Segments[1].Keys, 1) {
+				assert.Equal(t, m3u8.UnknownEncryption, mplist.Segments[1].Keys[0].Method)
+				assert.Equal(t, "SAMPLE-AES-CBC", mplist.Segments[1].Keys[0].MethodName)
+			}
+		}
+
+		_, ok := mplist.EffectiveIV(0, "urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed")
+		assert.False(t, ok, "should not derive the iv of a "+m3u8.SampleAESCTR.String()+" key")
+
+		var buf bytes.Buffer
+		if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(plist), "should successfully encode") {
+			assert.Contains(t, buf.String(), "#EXT-X-KEY:METHOD=SAMPLE-AES-CTR,")
+			assert.Contains(t, buf.String(), "#EXT-X-KEY:METHOD=SAMPLE-AES-CBC,URI=\"b.key\"\n")
+		}
+
+		assert.NotPanics(t, func() {
+			assert.Equal(t, "EncryptionMethod(42)", m3u8.EncryptionMethod(42).String())
+		})
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=NONE,URI=\"a.key\"\n#EXTINF:9,\nfirst.ts\n"))
+		assert.IsType(t, &m3u8.InvalidSyntaxError{}, err, "should reject attributes of keys without encryption")
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=SAMPLE-AES-CTR\n#EXTINF:9,\nfirst.ts\n"))
+		assert.IsType(t, &m3u8.InvalidSyntaxError{}, err, "should require the uri of encrypted segments")
+	})
+
+	t.Run("master playlist with content steering", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-CONTENT-STEERING:SERVER-URI=\"https://example.com/steering\",PATHWAY-ID=\"CDN-A\"\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",NAME=\"English\",STABLE-RENDITION-ID=\"en\",URI=\"a/en.m3u8\"\n#EXT-X-STREAM-INF:BANDWIDTH=1280000,CODECS=\"avc1.4d401f,mp4a.40.2\",AUDIO=\"aac\",PATHWAY-ID=\"CDN-A\",STABLE-VARIANT-ID=\"low\"\na/low.m3u8\n"
+
+		plist, err := m3u8.DecodePlaylist([]byte(data))
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MasterPlaylist)
+
+		if assert.NotNil(t, mplist.ContentSteering) {
+			assert.Equal(t, "https://example.com/steering", mplist.ContentSteering.ServerURI)
+			assert.Equal(t, "CDN-A", mplist.ContentSteering.PathwayID)
+		}
+
+		if assert.Len(t, mplist.VariantStreams, 1) {
+			assert.Equal(t, "CDN-A", mplist.VariantStreams[0].PathwayID)
+			assert.Equal(t, "low", mplist.VariantStreams[0].StableVariantID)
+		}
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1280000,PATHWAY-ID=\"CDN A\"\nlow.m3u8\n"))
+		assert.NotNil(t, err, "should reject invalid pathway ids")
+	})
+
+	t.Run("master playlist with video range and content protection attributes", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-VERSION:12\n#EXT-X-STREAM-INF:BANDWIDTH=9000000,CODECS=\"hvc1.2.4.L153.B0\",SUPPLEMENTAL-CODECS=\"dvh1.08.07/db4h\",VIDEO-RANGE=PQ,SCORE=2.5,HDCP-LEVEL=TYPE-1,ALLOWED-CPC=\"com.apple.streamingkeydelivery:HW/SW,com.example.drm:\",REQ-VIDEO-LAYOUT=\"CH-STEREO,CH-MONO\"\nhdr.m3u8\n#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=900000,VIDEO-RANGE=HLG,HDCP-LEVEL=NONE,URI=\"iframes.m3u8\"\n"
+
+		plist, err := m3u8.DecodePlaylist([]byte(data))
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MasterPlaylist)
+
+		if assert.Len(t, mplist.VariantStreams, 1) {
+			vs := mplist.VariantStreams[0]
+			assert.Equal(t, []string{"dvh1.08.07/db4h"}, vs.SupplementalCodecs)
+			assert.Equal(t, m3u8.PQ, vs.VideoRange)
+			if assert.NotNil(t, vs.Score) {
+				assert.Equal(t, 2.5, *vs.Score)
+			}
+			assert.Equal(t, m3u8.HDCPType1, vs.HDCPLevel)
+			assert.True(t, vs.HDCP)
+			assert.Equal(t, []string{"CH-STEREO", "CH-MONO"}, vs.ReqVideoLayout)
+			if assert.Len(t, vs.AllowedCPC, 2) {
+				assert.Equal(t, "com.apple.streamingkeydelivery", vs.AllowedCPC[0].KeyFormat)
+				assert.Equal(t, []string{"HW", "SW"}, vs.AllowedCPC[0].CPC)
+				assert.Equal(t, "com.example.drm", vs.AllowedCPC[1].KeyFormat)
+				assert.Empty(t, vs.AllowedCPC[1].CPC)
+			}
+		}
+
+		if assert.Len(t, mplist.IFrameStreams, 1) {
+			assert.Equal(t, m3u8.HLG, mplist.IFrameStreams[0].VideoRange)
+			assert.Equal(t, m3u8.HDCPNone, mplist.IFrameStreams[0].HDCPLevel)
+			assert.False(t, mplist.IFrameStreams[0].HDCP)
+			assert.Nil(t, mplist.IFrameStreams[0].Score)
+		}
+
+		// an explicit score of zero must survive a round trip
+		plist, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=9000000,SCORE=0\nlow.m3u8\n"))
+		if assert.Nil(t, err) {
+			var buf bytes.Buffer
+			if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(plist), "should successfully encode") {
+				assert.Contains(t, buf.String(), "SCORE=0")
+			}
+		}
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=9000000,REQ-VIDEO-LAYOUT=\"CH-STEREO\"\nhdr.m3u8\n"))
+		assert.IsType(t, &m3u8.CompatibilityVersionError{}, err)
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=9000000,VIDEO-RANGE=HDR10\nhdr.m3u8\n"))
+		assert.NotNil(t, err, "should reject unknown video ranges")
+	})
+
+	t.Run("master playlist with multiple rendition groups per variant stream", func(t *testing.T) {
+		const data = "#EXTM3U\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",NAME=\"English\",URI=\"en.m3u8\"\n#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=\"English\",URI=\"en.vtt.m3u8\"\n#EXT-X-STREAM-INF:BANDWIDTH=1280000,AUDIO=\"aac\",SUBTITLES=\"subs\",CLOSED-CAPTIONS=NONE\nlow.m3u8\n#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=128000,VIDEO=\"vid\",URI=\"iframes.m3u8\"\n"
+
+		plist, err := m3u8.DecodePlaylist([]byte(data))
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MasterPlaylist)
+
+		if assert.Len(t, mplist.VariantStreams, 1) {
+			vs := mplist.VariantStreams[0]
+			assert.Equal(t, "aac", vs.AudioGroupID)
+			assert.Equal(t, "subs", vs.SubtitlesGroupID)
+			assert.Equal(t, "subs", vs.Group(m3u8.Subtitles))
+			assert.Equal(t, "", vs.Group(m3u8.Video))
+			assert.True(t, vs.NoClosedCaptions)
+
+			// deprecated fields
+			assert.Equal(t, m3u8.Audio, vs.Type)
+			assert.Equal(t, "aac", vs.GroupID)
+		}
+
+		if assert.Len(t, mplist.IFrameStreams, 1) {
+			assert.Equal(t, "vid", mplist.IFrameStreams[0].VideoGroupID)
+		}
+
+		var buf bytes.Buffer
+		if !assert.Nil(t, m3u8.NewEncoder(&buf).Encode(&m3u8.MasterPlaylist{GenericPlaylist: &m3u8.GenericPlaylist{}, VariantStreams: mplist.VariantStreams}), "should successfully encode") {
+			t.FailNow()
+		}
+
+		plist, err = m3u8.DecodePlaylist(buf.Bytes())
+		if !assert.Nil(t, err, "should sucessfully parse the encoded playlist") {
+			t.FailNow()
+		}
+
+		if mplist := plist.(*m3u8.MasterPlaylist); assert.Len(t, mplist.VariantStreams, 1) {
+			vs := mplist.VariantStreams[0]
+			assert.Equal(t, "aac", vs.AudioGroupID)
+			assert.Equal(t, "subs", vs.SubtitlesGroupID)
+			assert.True(t, vs.NoClosedCaptions)
+
+			// the group id of the same type takes precedence over the
+			// deprecated GroupID field when it is edited after decoding
+			vs.AudioGroupID = "ac3"
+			buf.Reset()
+			if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(&m3u8.MasterPlaylist{GenericPlaylist: &m3u8.GenericPlaylist{}, VariantStreams: mplist.VariantStreams}), "should successfully encode") {
+				assert.Contains(t, buf.String(), `AUDIO="ac3"`)
+				assert.NotContains(t, buf.String(), `AUDIO="aac"`)
+			}
+		}
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1280000,CLOSED-CAPTIONS=YES\nlow.m3u8\n"))
+		assert.NotNil(t, err, "should reject enumerated closed captions other than none")
+	})
+	t.Run("media playlist with all errors", func(t *testing.T) {
+		const data = "#EXTM3U\n" +
+			"#EXT-X-TARGETDURATION:ten\n" +
+			"#EXT-X-UNKNOWN\n" +
+			"#EXTINF:9.009,\n" +
+			"first.ts\n" +
+			"#EXT-X-KEY:URI=\"key\"\n" +
+			"#EXTINF:10,\n" +
+			"second.ts\n" +
+			"#EXT-X-PROGRAM-DATE-TIME:yesterday\n" +
+			"#EXTINF:10,\n" +
+			"third.ts\n"
+
+		_, err := m3u8.DecodePlaylist([]byte(data))
+		assert.IsType(t, &m3u8.UnexpectedTagError{}, err, "should stop at the first error found by default")
+
+		d := m3u8.NewDecoder(strings.NewReader(data))
+		d.AllErrors = true
+
+		plist, err := d.Decode()
+		if !assert.NotNil(t, plist, "should return the best-effort playlist") {
+			t.FailNow()
+		}
+
+		var list m3u8.ErrorList
+		if assert.True(t, errors.As(err, &list)) && assert.Len(t, list, 5) {
+			for i, line := range []int{2, 3, 4, 6, 9} {
+				assert.Equal(t, line, list[i].(interface{ Line() int }).Line())
+			}
+		}
+
+		var unexpected *m3u8.UnexpectedTagError
+		assert.True(t, errors.As(err, &unexpected))
+
+		var compatibility *m3u8.CompatibilityVersionError
+		if assert.True(t, errors.As(err, &compatibility)) {
+			assert.Equal(t, 4, compatibility.Line())
+		}
+
+		mplist := plist.(*m3u8.MediaPlaylist)
+		if assert.Len(t, mplist.Segments, 3) {
+			assert.Equal(t, 9*time.Second+9*time.Millisecond, mplist.Segments[0].Duration)
+			assert.Empty(t, mplist.Segments[1].Keys)
+			assert.Equal(t, "", mplist.Segments[2].ProgramDateTime)
+		}
+
+		d = m3u8.NewDecoder(strings.NewReader("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\nfirst.ts\n"))
+		d.AllErrors = true
+
+		_, err = d.Decode()
+		assert.Nil(t, err, "should not return an empty error list")
+
+		// errors of a trailing segment without a uri
+		const trailing = "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXTINF:4,\n0.ts\n#EXT-X-PROGRAM-DATE-TIME:bad\n#EXT-X-KEY:URI=\"key\"\n#EXTINF:4,\n"
+
+		_, err = m3u8.DecodePlaylist([]byte(trailing))
+		if assert.IsType(t, &m3u8.InvalidSyntaxError{}, err) {
+			assert.Equal(t, 6, err.(*m3u8.InvalidSyntaxError).Line())
+		}
+
+		d = m3u8.NewDecoder(strings.NewReader(trailing))
+		d.AllErrors = true
+
+		_, err = d.Decode()
+		list = nil
+		if assert.True(t, errors.As(err, &list)) && assert.Len(t, list, 2) {
+			assert.Equal(t, 6, list[0].(interface{ Line() int }).Line())
+			assert.Equal(t, 7, list[1].(interface{ Line() int }).Line())
+		}
+
+		for name, tt := range map[string]struct {
+			data     string
+			line     int
+			segments int
+		}{
+			"invalid version number":            {"#EXTM3U\n#EXT-X-VERSION:three\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\n0.ts\n", 2, 1},
+			"media segment after the end list":  {"#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\n0.ts\n#EXT-X-ENDLIST\n#EXTINF:10,\n1.ts\n", 6, 2},
+			"partial segments without part inf": {"#EXTM3U\n#EXT-X-VERSION:9\n#EXT-X-TARGETDURATION:4\n#EXT-X-PART:DURATION=2,URI=\"0.0.mp4\"\n#EXTINF:4,\n0.mp4\n", 4, 1},
+		} {
+			_, err = m3u8.DecodePlaylist([]byte(tt.data))
+			if assert.IsType(t, &m3u8.InvalidSyntaxError{}, err, name) {
+				assert.Equal(t, tt.line, err.(*m3u8.InvalidSyntaxError).Line(), name)
+			}
+
+			d = m3u8.NewDecoder(strings.NewReader(tt.data))
+			d.AllErrors = true
+
+			plist, err = d.Decode()
+			list = nil
+			if assert.True(t, errors.As(err, &list), name) && assert.Len(t, list, 1, name) {
+				assert.Equal(t, tt.line, list[0].(interface{ Line() int }).Line(), name)
+			}
+
+			if assert.NotNil(t, plist, name) {
+				assert.Len(t, plist.(*m3u8.MediaPlaylist).Segments, tt.segments, name)
+			}
+		}
+
+		d = m3u8.NewDecoder(strings.NewReader("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-STREAM-INF:BANDWIDTH=1\nlow.m3u8\n"))
+		d.AllErrors = true
+
+		_, err = d.Decode()
+		assert.True(t, errors.Is(err, m3u8.ErrMixedTags), "should still fail on unrecoverable errors")
+	})
 }
@@ -0,0 +1,173 @@
+package m3u8
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Definition represents the attributes associated with an EXT-X-DEFINE tag.
+// It provides a variable that can be referenced by URI lines and
+// quoted-string attribute values using the "{$name}" syntax.
+//
+// Exactly one of NAME, IMPORT or QUERYPARAM is encoded, depending on the
+// Import and QueryParam values.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.2.3.
+//
+// Use of Definition REQUIRES a compatibility version number of 8 or greater.
+type Definition struct {
+	// Name is the name of the variable. It may only contain alphanumeric
+	// characters, hyphens and underscores.
+	//
+	// Name is REQUIRED.
+	Name string
+
+	// Value is the value of the variable. If Import or QueryParam is true, it
+	// is the value that was resolved while decoding and it is not encoded.
+	Value string
+
+	// Import indicates that the variable is imported from the Master Playlist
+	// that refers to the Media Playlist. It MUST NOT be used in a Master
+	// Playlist.
+	Import bool
+
+	// QueryParam indicates that the variable is taken from the query
+	// parameter with the same name in the URI of the Playlist.
+	//
+	// Use of QueryParam REQUIRES a compatibility version number of 11 or
+	// greater.
+	QueryParam bool
+}
+
+var (
+	rxVariableName      = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	rxVariableReference = regexp.MustCompile(`\{\$([a-zA-Z0-9_-]+)\}`)
+)
+
+func parseDefinition(meta string) (*Definition, error) {
+	attrs, err := parseAttributeList(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var def Definition
+	var found int
+	if name, err := attrs.string(attrName); err == nil {
+		def.Value, err = attrs.string(attrValue)
+		if err != nil {
+			return nil, err
+		}
+
+		def.Name = name
+		found++
+	} else if !isMissingAttr(err) {
+		return nil, err
+	}
+
+	if name, err := attrs.string(attrImport); err == nil {
+		def.Name = name
+		def.Import = true
+		found++
+	} else if !isMissingAttr(err) {
+		return nil, err
+	}
+
+	if name, err := attrs.string(attrQueryParam); err == nil {
+		def.Name = name
+		def.QueryParam = true
+		found++
+	} else if !isMissingAttr(err) {
+		return nil, err
+	}
+
+	if found != 1 {
+		return nil, &Error{`this tag must have exactly one of the attributes, "` + attrName + `", "` + attrImport + `", or "` + attrQueryParam + `",`}
+	}
+
+	if !rxVariableName.MatchString(def.Name) {
+		return nil, &Error{`invalid variable name, "` + def.Name + `",`}
+	}
+
+	return &def, nil
+}
+
+func (def *Definition) attrs() (attributes, error) {
+	if !rxVariableName.MatchString(def.Name) {
+		return nil, &Error{`invalid variable name, "` + def.Name + `",`}
+	}
+
+	switch {
+	case def.Import && def.QueryParam:
+		return nil, &Error{"a definition cannot be both imported and a query parameter"}
+
+	case def.Import:
+		return attributes{attrImport: def.Name}, nil
+
+	case def.QueryParam:
+		return attributes{attrQueryParam: def.Name}, nil
+
+	}
+
+	return attributes{
+		attrName:  def.Name,
+		attrValue: def.Value,
+	}, nil
+}
+
+// Variables returns the values of the variables defined by the playlist,
+// keyed by name.
+func (p *GenericPlaylist) Variables() map[string]string {
+	vars := make(map[string]string, len(p.Definitions))
+	for _, def := range p.Definitions {
+		vars[def.Name] = def.Value
+	}
+
+	return vars
+}
+
+// Expand replaces the variable references in str with the values of the
+// variables defined by the playlist.
+//
+// An error is returned if str refers to an undefined variable.
+func (p *GenericPlaylist) Expand(str string) (string, error) {
+	return substituteVariables(str, p.Variables())
+}
+
+func substituteVariables(str string, vars map[string]string) (string, error) {
+	if strings.IndexByte(str, '{') == -1 {
+		return str, nil
+	}
+
+	var err error
+	str = rxVariableReference.ReplaceAllStringFunc(str, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		value, ok := vars[name]
+		if !ok && err == nil {
+			err = &Error{`undefined variable, "` + name + `",`}
+		}
+
+		return value
+	})
+
+	return str, err
+}
+
+// substituteQuotedStrings replaces the variable references in the
+// quoted-string values of an attribute list.
+func substituteQuotedStrings(meta string, vars map[string]string) (string, error) {
+	if strings.IndexByte(meta, '"') == -1 {
+		return meta, nil
+	}
+
+	parts := strings.Split(meta, `"`)
+	for i := 1; i < len(parts); i += 2 {
+		value, err := substituteVariables(parts[i], vars)
+		if err != nil {
+			return "", err
+		}
+
+		parts[i] = value
+	}
+
+	return strings.Join(parts, `"`), nil
+}
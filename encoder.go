@@ -4,6 +4,39 @@ import "io"
 
 type Encoder struct {
 	w io.Writer
+
+	// PreserveAttributeOrder encodes the attributes of decoded tags in the
+	// order in which they were decoded, as recorded by the Layout of playlists
+	// that were decoded in lossless mode. Otherwise, and for attributes that
+	// were added after decoding, the attributes of each tag are encoded in
+	// the order conventionally used by the specification, followed by any
+	// other attributes in lexical order.
+	PreserveAttributeOrder bool
+
+	// AutoVersion encodes the minimum compatibility version number that is
+	// required by the playlist, as returned by RequiredVersion, instead of its
+	// Version value. The playlist itself is not modified.
+	//
+	// The required version is also encoded for playlists with a zero Version
+	// value.
+	AutoVersion bool
+
+	// CheckVersion rejects playlists with a non-zero Version value that is
+	// lower than the one returned by RequiredVersion. It has no effect with
+	// AutoVersion.
+	CheckVersion bool
+
+	// OmitRepeatedKeysAndMaps omits the EXT-X-KEY and EXT-X-MAP tags of Media
+	// Segments with a Key or Map value that is already in effect, as returned
+	// by EffectiveKeys and EffectiveMap, so that the values can be set on
+	// every Media Segment of a Media Playlist. The playlist itself is not
+	// modified.
+	OmitRepeatedKeysAndMaps bool
+
+	tags tagHandlers
+
+	// layout is the Layout of the playlist being encoded
+	layout *Layout
 }
 
 func NewEncoder(w io.Writer) *Encoder {
@@ -12,6 +45,44 @@ func NewEncoder(w io.Writer) *Encoder {
 	}
 }
 
+// RegisterTag registers the handler for the custom tag with the given name,
+// such as "#EXT-X-CUE-OUT". The Encode function of the handler is used to
+// encode the CustomTags with the same name.
+//
+// RegisterTag panics if the name does not start with "#EXT".
+func (e *Encoder) RegisterTag(name string, h *TagHandler) {
+	e.tags.register(name, h)
+}
+
 func (e *Encoder) Encode(plist Playlist) error {
-	return plist.encode(e.w)
+	defer func() { e.layout = nil }()
+	return plist.encode(e.w, e)
+}
+
+// attributeOrder returns the order in which the attributes of the tag of the
+// element v are encoded.
+func (e *Encoder) attributeOrder(tag string, v interface{}) attributeOrder {
+	decoded := e.layout.order(v)
+	if !e.PreserveAttributeOrder || len(decoded) == 0 {
+		return attributeOrders[tag]
+	}
+
+	return append(decoded[:len(decoded):len(decoded)], attributeOrders[tag]...)
+}
+
+// version returns the compatibility version number to be encoded for the
+// playlist, given its declared version.
+func (e *Encoder) version(p Playlist, declared int) (int, error) {
+	switch {
+	case e.AutoVersion, declared == 0:
+		return RequiredVersion(p), nil
+
+	case e.CheckVersion:
+		if required := RequiredVersion(p); declared < required && required > 1 {
+			return 0, &CompatibilityVersionError{version: required}
+		}
+
+	}
+
+	return declared, nil
 }
@@ -0,0 +1,483 @@
+package m3u8_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ssttevee/m3u8"
+	"github.com/stretchr/testify/assert"
+)
+
+func roundTrip(t *testing.T, data string) (m3u8.Playlist, m3u8.Playlist) {
+	plist, err := m3u8.DecodePlaylist([]byte(data))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+	if !assert.Nil(t, m3u8.NewEncoder(&buf).Encode(plist), "should successfully encode") {
+		t.FailNow()
+	}
+
+	decoded, err := m3u8.DecodePlaylist(buf.Bytes())
+	if !assert.Nil(t, err, "should sucessfully parse the encoded playlist:\n"+buf.String()) {
+		t.FailNow()
+	}
+
+	// the layouts of the playlists refer to different lines and elements
+	return withoutLayout(plist), withoutLayout(decoded)
+}
+
+func withoutLayout(plist m3u8.Playlist) m3u8.Playlist {
+	switch plist := plist.(type) {
+	case *m3u8.MediaPlaylist:
+		plist.Layout = nil
+
+	case *m3u8.MasterPlaylist:
+		plist.Layout = nil
+
+	}
+
+	return plist
+}
+
+func TestEncodePlaylist(t *testing.T) {
+	t.Run("master playlist round trip", func(t *testing.T) {
+		const data = "#EXTM3U\n" +
+			"#EXT-X-VERSION:8\n" +
+			"#EXT-X-INDEPENDENT-SEGMENTS\n" +
+			"#EXT-X-START:TIME-OFFSET=-12.5,PRECISE=YES\n" +
+			"#EXT-X-DEFINE:NAME=\"cdn\",VALUE=\"https://cdn.example.com\"\n" +
+			"#EXT-X-CONTENT-STEERING:SERVER-URI=\"https://example.com/steering\",PATHWAY-ID=\"CDN-A\"\n" +
+			"#EXT-X-SESSION-DATA:DATA-ID=\"com.example.lyrics\",URI=\"lyrics.json\"\n" +
+			"#EXT-X-SESSION-DATA:DATA-ID=\"com.example.title\",VALUE=\"This is an example\",LANGUAGE=\"en\"\n" +
+			"#EXT-X-SESSION-KEY:METHOD=SAMPLE-AES,URI=\"skd://key\",IV=0x0123456789abcdef0123456789abcdef,KEYFORMAT=\"com.apple.streamingkeydelivery\",KEYFORMATVERSIONS=\"1\"\n" +
+			"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",NAME=\"English\",LANGUAGE=\"en\",DEFAULT=YES,AUTOSELECT=YES,CHANNELS=\"2\",URI=\"{$cdn}/en.m3u8\"\n" +
+			"#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID=\"vid\",NAME=\"Main\",DEFAULT=YES,URI=\"main.m3u8\"\n" +
+			"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=\"English\",FORCED=YES,CHARACTERISTICS=\"public.accessibility.transcribes-spoken-dialog\",URI=\"en.vtt.m3u8\"\n" +
+			"#EXT-X-MEDIA:TYPE=CLOSED-CAPTIONS,GROUP-ID=\"cc\",NAME=\"English\",INSTREAM-ID=\"CC1\"\n" +
+			"#EXT-X-STREAM-INF:BANDWIDTH=1280000,AVERAGE-BANDWIDTH=1000000,CODECS=\"avc1.4d401f,mp4a.40.2\",RESOLUTION=1280x720,FRAME-RATE=29.97,HDCP-LEVEL=TYPE-0,VIDEO-RANGE=SDR,STABLE-VARIANT-ID=\"low\",AUDIO=\"aac\",VIDEO=\"vid\",SUBTITLES=\"subs\",CLOSED-CAPTIONS=\"cc\",PATHWAY-ID=\"CDN-A\"\n" +
+			"{$cdn}/low.m3u8\n" +
+			"\n" +
+			"#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=128000,CODECS=\"avc1.4d401f\",RESOLUTION=1280x720,VIDEO=\"vid\",URI=\"iframes.m3u8\"\n"
+
+		expected, actual := roundTrip(t, data)
+		assert.Equal(t, expected, actual)
+
+		mplist := actual.(*m3u8.MasterPlaylist)
+		assert.Len(t, mplist.RenditionMap, 4)
+		assert.Len(t, mplist.VariantStreams, 1)
+		assert.Len(t, mplist.IFrameStreams, 1)
+		assert.Len(t, mplist.SessionData, 2)
+		assert.Len(t, mplist.SessionKeys, 1)
+		if assert.NotNil(t, mplist.Start) {
+			assert.True(t, mplist.Start.Precise)
+		}
+	})
+
+	t.Run("master playlist with invalid session data", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := m3u8.NewEncoder(&buf).Encode(&m3u8.MasterPlaylist{
+			GenericPlaylist: &m3u8.GenericPlaylist{},
+			SessionData:     m3u8.SessionData{{ID: "com.example.title", Value: "title", URI: "title.json"}},
+		})
+		assert.Equal(t, m3u8.ErrSessionDataValueAndURI, err)
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-SESSION-DATA:DATA-ID=\"com.example.title\",VALUE=\"title\",URI=\"title.json\"\n"))
+		assert.NotNil(t, err, "should reject session data with both value and uri")
+	})
+
+	t.Run("master playlist with unencrypted session key", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := m3u8.NewEncoder(&buf).Encode(&m3u8.MasterPlaylist{
+			GenericPlaylist: &m3u8.GenericPlaylist{},
+			SessionKeys:     []*m3u8.Key{{Method: m3u8.NoEncryption}},
+		})
+		assert.Equal(t, m3u8.ErrSessionKeyNone, err)
+
+		_, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-SESSION-KEY:METHOD=NONE\n"))
+		assert.NotNil(t, err, "should reject session keys without encryption")
+	})
+}
+
+func TestEncodeAttributeOrder(t *testing.T) {
+	const data = "#EXTM3U\n" +
+		"#EXT-X-VERSION:7\n" +
+		"#EXT-X-SESSION-DATA:LANGUAGE=\"en\",DATA-ID=\"com.example.title\",VALUE=\"Example\"\n" +
+		"#EXT-X-MEDIA:URI=\"en.m3u8\",NAME=\"English\",GROUP-ID=\"aac\",TYPE=AUDIO,DEFAULT=YES,LANGUAGE=\"en\"\n" +
+		"#EXT-X-STREAM-INF:CODECS=\"avc1.4d401f,mp4a.40.2\",AUDIO=\"aac\",RESOLUTION=1280x720,BANDWIDTH=1280000\n" +
+		"low.m3u8\n"
+
+	const conventional = "#EXTM3U\n" +
+		"#EXT-X-VERSION:7\n" +
+		"#EXT-X-SESSION-DATA:DATA-ID=\"com.example.title\",VALUE=\"Example\",LANGUAGE=\"en\"\n" +
+		"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",NAME=\"English\",LANGUAGE=\"en\",DEFAULT=YES,URI=\"en.m3u8\"\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1280000,CODECS=\"avc1.4d401f,mp4a.40.2\",RESOLUTION=1280x720,AUDIO=\"aac\"\n" +
+		"low.m3u8\n" +
+		"\n"
+
+	plist, err := m3u8.DecodePlaylist([]byte(data))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	t.Run("conventional", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			var buf bytes.Buffer
+			if !assert.Nil(t, m3u8.NewEncoder(&buf).Encode(plist), "should successfully encode") {
+				t.FailNow()
+			}
+
+			assert.Equal(t, conventional, buf.String())
+		}
+	})
+
+	t.Run("comparable", func(t *testing.T) {
+		// the layouts of the playlists record the lines of different elements
+		one, err := m3u8.DecodePlaylist([]byte(data))
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		other, err := m3u8.DecodePlaylist([]byte(data))
+		if assert.Nil(t, err, "should sucessfully parse") {
+			assert.True(t, reflect.DeepEqual(withoutLayout(one), withoutLayout(other)), "should not retain the attribute order outside of lossless mode")
+		}
+	})
+
+	t.Run("not retained", func(t *testing.T) {
+		var buf bytes.Buffer
+		e := m3u8.NewEncoder(&buf)
+		e.PreserveAttributeOrder = true
+		if assert.Nil(t, e.Encode(plist), "should successfully encode") {
+			assert.Equal(t, conventional, buf.String())
+		}
+	})
+
+	t.Run("preserved", func(t *testing.T) {
+		d := m3u8.NewDecoder(bytes.NewReader([]byte(data)))
+		d.Lossless = true
+
+		plist, err := d.Decode()
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MasterPlaylist)
+		mplist.VariantStreams[0].FrameRate = 29.97
+		mplist.VariantStreams[0].AverageBandwidth = 1000000
+
+		var buf bytes.Buffer
+		e := m3u8.NewEncoder(&buf)
+		e.PreserveAttributeOrder = true
+		if !assert.Nil(t, e.Encode(plist), "should successfully encode") {
+			t.FailNow()
+		}
+
+		// attributes that were added after decoding follow the decoded ones
+		assert.Equal(t, "#EXTM3U\n"+
+			"#EXT-X-VERSION:7\n"+
+			"#EXT-X-SESSION-DATA:LANGUAGE=\"en\",DATA-ID=\"com.example.title\",VALUE=\"Example\"\n"+
+			"#EXT-X-MEDIA:URI=\"en.m3u8\",NAME=\"English\",GROUP-ID=\"aac\",TYPE=AUDIO,DEFAULT=YES,LANGUAGE=\"en\"\n"+
+			"#EXT-X-STREAM-INF:CODECS=\"avc1.4d401f,mp4a.40.2\",AUDIO=\"aac\",RESOLUTION=1280x720,BANDWIDTH=1280000,AVERAGE-BANDWIDTH=1000000,FRAME-RATE=29.97\n"+
+			"low.m3u8\n"+
+			"\n", buf.String())
+	})
+}
+
+func TestEncodeLosslessPlaylist(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "lossless", "*.m3u8"))
+	if !assert.Nil(t, err) || !assert.NotEmpty(t, files) {
+		t.FailNow()
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if !assert.Nil(t, err) {
+				t.FailNow()
+			}
+
+			d := m3u8.NewDecoder(bytes.NewReader(data))
+			d.Lossless = true
+
+			plist, err := d.Decode()
+			if !assert.Nil(t, err, "should sucessfully parse") {
+				t.FailNow()
+			}
+
+			var buf bytes.Buffer
+			e := m3u8.NewEncoder(&buf)
+			e.PreserveAttributeOrder = true
+			if !assert.Nil(t, e.Encode(plist), "should successfully encode") {
+				t.FailNow()
+			}
+
+			assert.Equal(t, string(data), buf.String(), "should be identical to the input")
+		})
+	}
+
+	t.Run("raw lines are dropped without lossless mode", func(t *testing.T) {
+		d := m3u8.NewDecoder(bytes.NewReader([]byte("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n# comment\n#EXT-X-UNKNOWN\n#EXTINF:9.009,\nfirst.ts\n")))
+		d.Strict = false
+
+		plist, err := d.Decode()
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		mplist := plist.(*m3u8.MediaPlaylist)
+		assert.Empty(t, mplist.RawLines)
+		if assert.Len(t, mplist.Segments, 1) {
+			assert.Empty(t, mplist.Segments[0].RawLines)
+		}
+	})
+}
+
+func TestRequiredVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected int
+	}{
+		{
+			name:     "integer durations",
+			data:     "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\nfirst.ts\n",
+			expected: 1,
+		},
+		{
+			name:     "iv",
+			data:     "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=AES-128,URI=\"key\",IV=0x1\n#EXTINF:10,\nfirst.ts\n",
+			expected: 2,
+		},
+		{
+			name:     "decimal durations",
+			data:     "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXTINF:9.009,\nfirst.ts\n",
+			expected: 3,
+		},
+		{
+			name:     "byte range",
+			data:     "#EXTM3U\n#EXT-X-VERSION:4\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\n#EXT-X-BYTERANGE:1000@0\nfirst.ts\n",
+			expected: 4,
+		},
+		{
+			name:     "sample aes",
+			data:     "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"key\"\n#EXTINF:10,\nfirst.ts\n",
+			expected: 5,
+		},
+		{
+			name:     "define",
+			data:     "#EXTM3U\n#EXT-X-VERSION:8\n#EXT-X-DEFINE:NAME=\"cdn\",VALUE=\"https://cdn.example.com\"\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\n{$cdn}/first.ts\n",
+			expected: 8,
+		},
+		{
+			name:     "service closed captions",
+			data:     "#EXTM3U\n#EXT-X-MEDIA:TYPE=CLOSED-CAPTIONS,GROUP-ID=\"cc\",NAME=\"English\",INSTREAM-ID=\"SERVICE1\"\n#EXT-X-STREAM-INF:BANDWIDTH=1280000,CLOSED-CAPTIONS=\"cc\"\nlow.m3u8\n",
+			expected: 7,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plist, err := m3u8.DecodePlaylist([]byte(test.data))
+			if !assert.Nil(t, err, "should sucessfully parse") {
+				t.FailNow()
+			}
+
+			assert.Equal(t, test.expected, m3u8.RequiredVersion(plist))
+		})
+	}
+
+	t.Run("map", func(t *testing.T) {
+		plist := &m3u8.MediaPlaylist{
+			GenericPlaylist: &m3u8.GenericPlaylist{},
+			Segments: []*m3u8.MediaSegment{
+				{URI: "first.mp4", Duration: 10 * time.Second, Map: &m3u8.Map{URI: "init.mp4"}},
+			},
+		}
+
+		assert.Equal(t, 6, m3u8.RequiredVersion(plist))
+
+		plist.IFramesOnly = true
+		assert.Equal(t, 5, m3u8.RequiredVersion(plist))
+	})
+}
+
+func TestEncodeEndList(t *testing.T) {
+	plist := &m3u8.MediaPlaylist{
+		GenericPlaylist: &m3u8.GenericPlaylist{Version: 3},
+		TargetDuration:  10,
+		Segments: []*m3u8.MediaSegment{
+			{URI: "first.ts", Duration: 9009 * time.Millisecond},
+		},
+	}
+
+	var buf bytes.Buffer
+	if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(plist)) {
+		assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXTINF:9.009,\nfirst.ts\n#EXT-X-ENDLIST\n", buf.String())
+	}
+
+	// live playlists must not be ended
+	plist.Live = true
+	buf.Reset()
+	if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(plist)) {
+		assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXTINF:9.009,\nfirst.ts\n", buf.String())
+	}
+}
+
+func TestEncodeSegmentTagOrder(t *testing.T) {
+	plist := &m3u8.MediaPlaylist{
+		GenericPlaylist: &m3u8.GenericPlaylist{Version: 6},
+		TargetDuration:  4,
+		PartTarget:      2 * time.Second,
+		Segments: []*m3u8.MediaSegment{
+			{
+				URI:             "0.mp4",
+				Duration:        4 * time.Second,
+				ByteRange:       &m3u8.ByteRange{Length: 100},
+				Discontinuity:   true,
+				Keys:            []*m3u8.Key{{Method: m3u8.AES128, URI: "a.key"}},
+				Map:             &m3u8.Map{URI: "init.mp4"},
+				ProgramDateTime: "2019-01-01T00:00:00.000Z",
+				Parts: []*m3u8.PartialSegment{
+					{URI: "0.0.mp4", Duration: 2 * time.Second},
+					{URI: "0.1.mp4", Duration: 2 * time.Second},
+				},
+			},
+			{URI: "1.mp4", Duration: 4 * time.Second},
+		},
+	}
+
+	// the tags that apply to a media segment precede its partial segments,
+	// which in turn precede the EXTINF tag so that it stays next to the uri
+	var buf bytes.Buffer
+	if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(plist)) {
+		assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:4\n#EXT-X-PART-INF:PART-TARGET=2\n"+
+			"#EXT-X-DISCONTINUITY\n"+
+			"#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n"+
+			"#EXT-X-MAP:URI=\"init.mp4\"\n"+
+			"#EXT-X-PROGRAM-DATE-TIME:2019-01-01T00:00:00.000Z\n"+
+			"#EXT-X-PART:DURATION=2,URI=\"0.0.mp4\"\n"+
+			"#EXT-X-PART:DURATION=2,URI=\"0.1.mp4\"\n"+
+			"#EXTINF:4,\n#EXT-X-BYTERANGE:100@0\n0.mp4\n"+
+			"#EXTINF:4,\n1.mp4\n"+
+			"#EXT-X-ENDLIST\n", buf.String())
+	}
+}
+
+func TestEncodeDeprecatedKey(t *testing.T) {
+	plist := &m3u8.MediaPlaylist{
+		GenericPlaylist: &m3u8.GenericPlaylist{Version: 3},
+		TargetDuration:  10,
+		Segments: []*m3u8.MediaSegment{
+			{URI: "first.ts", Duration: 10 * time.Second, Key: &m3u8.Key{Method: m3u8.AES128, URI: "a.key"}},
+			{URI: "second.ts", Duration: 10 * time.Second, Key: &m3u8.Key{Method: m3u8.AES128, URI: "a.key"}, Keys: []*m3u8.Key{{Method: m3u8.AES128, URI: "b.key"}}},
+		},
+	}
+
+	// the deprecated key is only used without keys
+	var buf bytes.Buffer
+	if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(plist)) {
+		assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n"+
+			"#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n#EXTINF:10,\nfirst.ts\n"+
+			"#EXT-X-KEY:METHOD=AES-128,URI=\"b.key\"\n#EXTINF:10,\nsecond.ts\n"+
+			"#EXT-X-ENDLIST\n", buf.String())
+	}
+
+	if keys := plist.EffectiveKeys(0); assert.Len(t, keys, 1) {
+		assert.Equal(t, "a.key", keys[0].URI)
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	plist := &m3u8.MediaPlaylist{
+		GenericPlaylist: &m3u8.GenericPlaylist{},
+		TargetDuration:  10,
+		Segments: []*m3u8.MediaSegment{
+			{URI: "first.ts", Duration: 9009 * time.Millisecond},
+		},
+	}
+
+	t.Run("undeclared", func(t *testing.T) {
+		// the required version is encoded for playlists without a version
+		var buf bytes.Buffer
+		if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(plist)) {
+			assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXTINF:9.009,\nfirst.ts\n#EXT-X-ENDLIST\n", buf.String())
+		}
+
+		assert.Equal(t, 0, plist.Version, "should not modify the playlist")
+	})
+
+	t.Run("declared", func(t *testing.T) {
+		// the declared version is encoded as is, even if it is too low
+		plist := *plist
+		plist.GenericPlaylist = &m3u8.GenericPlaylist{Version: 2}
+
+		var buf bytes.Buffer
+		if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(&plist)) {
+			assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:2\n#EXT-X-TARGETDURATION:10\n#EXTINF:9.009,\nfirst.ts\n#EXT-X-ENDLIST\n", buf.String())
+		}
+	})
+
+	t.Run("automatic", func(t *testing.T) {
+		var buf bytes.Buffer
+		e := m3u8.NewEncoder(&buf)
+		e.AutoVersion = true
+		if assert.Nil(t, e.Encode(plist)) {
+			assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXTINF:9.009,\nfirst.ts\n#EXT-X-ENDLIST\n", buf.String())
+		}
+
+		assert.Equal(t, 0, plist.Version, "should not modify the playlist")
+	})
+
+	t.Run("checked", func(t *testing.T) {
+		e := m3u8.NewEncoder(&bytes.Buffer{})
+		e.CheckVersion = true
+		assert.Nil(t, e.Encode(plist), "should encode the required version")
+
+		plist.Version = 2
+		err := e.Encode(plist)
+		if assert.IsType(t, &m3u8.CompatibilityVersionError{}, err) {
+			assert.Equal(t, "m3u8: compatibility version number, 3, required", err.Error())
+		}
+
+		plist.Version = 3
+		assert.Nil(t, e.Encode(plist))
+	})
+}
+
+func TestEncodeOmitRepeatedKeysAndMaps(t *testing.T) {
+	key := &m3u8.Key{Method: m3u8.AES128, URI: "a.key"}
+	init := &m3u8.Map{URI: "init.mp4"}
+
+	plist := &m3u8.MediaPlaylist{
+		GenericPlaylist: &m3u8.GenericPlaylist{Version: 6},
+		TargetDuration:  4,
+		Segments: []*m3u8.MediaSegment{
+			{URI: "0.mp4", Duration: 4 * time.Second, Keys: []*m3u8.Key{key}, Map: init},
+			{URI: "1.mp4", Duration: 4 * time.Second, Keys: []*m3u8.Key{{Method: m3u8.AES128, URI: "a.key"}}, Map: &m3u8.Map{URI: "init.mp4"}},
+			{URI: "2.mp4", Duration: 4 * time.Second, Keys: []*m3u8.Key{{Method: m3u8.NoEncryption}}, Map: init},
+			{URI: "3.mp4", Duration: 4 * time.Second, Keys: []*m3u8.Key{{Method: m3u8.NoEncryption}}},
+			{URI: "4.mp4", Duration: 4 * time.Second, Keys: []*m3u8.Key{key}},
+		},
+	}
+
+	var buf bytes.Buffer
+	e := m3u8.NewEncoder(&buf)
+	e.OmitRepeatedKeysAndMaps = true
+	if assert.Nil(t, e.Encode(plist)) {
+		assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:4\n"+
+			"#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:4,\n0.mp4\n"+
+			"#EXTINF:4,\n1.mp4\n"+
+			"#EXT-X-KEY:METHOD=NONE\n#EXTINF:4,\n2.mp4\n"+
+			"#EXTINF:4,\n3.mp4\n"+
+			"#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n#EXTINF:4,\n4.mp4\n"+
+			"#EXT-X-ENDLIST\n", buf.String())
+	}
+
+	assert.Len(t, plist.Segments[1].Keys, 1, "should not modify the playlist")
+}
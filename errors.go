@@ -2,6 +2,7 @@ package m3u8
 
 import (
 	"fmt"
+	"sort"
 )
 
 var (
@@ -13,12 +14,19 @@ var (
 	ErrBadAttrSyntax          = &Error{"invalid attribute syntax"}
 	ErrBadEncryptionMethod    = &Error{"invalid encryption method"}
 	ErrBadPlaylistType        = &Error{"invalid playlist type"}
+	ErrBadPreloadHintType     = &Error{"invalid preload hint type"}
+	ErrBadHDCPLevel           = &Error{"invalid hdcp level"}
+	ErrBadVideoRange          = &Error{"invalid video range"}
 	ErrNoRangeStart           = &Error{"missing range start"}
 	ErrNotASegment            = &Error{"not a segment"}
 	ErrUnexpectedMediaSegment = &Error{"found media segment after a " + endlistTag + " tag"}
 	ErrMissingURI             = &Error{"missing uri"}
 	ErrUnexpectedURI          = &Error{"unexpected uri"}
 	ErrBadVersionNumber       = &Error{"invalid version number"}
+	ErrSessionDataValueAndURI = &Error{"session data must have exactly one of value or uri"}
+	ErrSessionKeyNone         = &Error{"session key method must not be " + NoEncryption.String()}
+	ErrBadKeyLength           = &Error{"invalid key length"}
+	ErrBadPadding             = &Error{"invalid padding"}
 )
 
 type Error struct {
@@ -56,7 +64,12 @@ func (e *invalidAttributeValueError) Error() string {
 type UnexpectedTagError split
 
 func (e *UnexpectedTagError) Error() string {
-	return fmt.Sprintf(`m3u8: unexpected tag in "%s"`, (*split)(e).line())
+	return fmt.Sprintf(`m3u8: unexpected tag on line %d (%s)`, e.num, (*split)(e).line())
+}
+
+// Line returns the number of the line that contains the tag.
+func (e *UnexpectedTagError) Line() int {
+	return e.num
 }
 
 type CompatibilityVersionError struct {
@@ -66,6 +79,10 @@ type CompatibilityVersionError struct {
 }
 
 func (e *CompatibilityVersionError) Error() string {
+	if e.split == nil {
+		return fmt.Sprintf(`m3u8: compatibility version number, %d, required`, e.version)
+	}
+
 	return fmt.Sprintf(`m3u8: compatibility version number, %d, required on line %d (%s)`, e.version, e.num, e.line())
 }
 
@@ -96,6 +113,9 @@ func isew(s *split, err error) error {
 	case *missingRequiredAttrError:
 		return ise(s, err.msg())
 
+	case *invalidAttributeValueError:
+		return ise(s, err.msg())
+
 	case *CompatibilityVersionError:
 		return &CompatibilityVersionError{s, err.version}
 
@@ -106,3 +126,44 @@ func isew(s *split, err error) error {
 
 	return err
 }
+
+// errorCollector collects the errors of a Decoder that continues past
+// recoverable errors.
+type errorCollector struct {
+	enabled bool
+	errs    ErrorList
+}
+
+// handle returns err if it must stop decoding, or nil if it was collected.
+// Only errors that refer to a line of the playlist are recoverable.
+func (c *errorCollector) handle(err error) error {
+	if !c.enabled {
+		return err
+	}
+
+	switch err.(type) {
+	case *InvalidSyntaxError, *CompatibilityVersionError, *UnexpectedTagError:
+		c.errs = append(c.errs, err)
+		return nil
+	}
+
+	return err
+}
+
+// err returns the collected errors ordered by line number, or nil if there
+// are none.
+func (c *errorCollector) err() error {
+	sort.SliceStable(c.errs, func(i, j int) bool {
+		return errorLine(c.errs[i]) < errorLine(c.errs[j])
+	})
+
+	return c.errs.err()
+}
+
+func errorLine(err error) int {
+	if err, ok := err.(interface{ Line() int }); ok {
+		return err.Line()
+	}
+
+	return 0
+}
@@ -0,0 +1,224 @@
+package m3u8
+
+import (
+	"strings"
+)
+
+// RenditionGroup is a set of Renditions in a Master Playlist that have the
+// same type and GroupID value.
+//
+// See https://tools.ietf.org/html/rfc8216#section-4.3.4.1.1.
+type RenditionGroup struct {
+	// Type is the type of every Rendition in the group.
+	Type MediaType
+
+	// ID is the GroupID value of every Rendition in the group.
+	ID string
+
+	// Renditions are the members of the group, in playlist order.
+	Renditions []Rendition
+
+	// Default is the member whose Default value is true, or nil if there is
+	// none.
+	Default Rendition
+
+	// AutoSelect are the members whose AutoSelect value is true.
+	AutoSelect []Rendition
+}
+
+// VariantRenditions relates a Variant Stream to the groups of Renditions
+// that it refers to.
+type VariantRenditions struct {
+	Variant *VariantStream
+
+	// Audio is the group referred to by the AUDIO attribute, or nil.
+	Audio *RenditionGroup
+
+	// Video is the group referred to by the VIDEO attribute, or nil.
+	Video *RenditionGroup
+
+	// Subtitles is the group referred to by the SUBTITLES attribute, or nil.
+	Subtitles *RenditionGroup
+
+	// ClosedCaptions is the group referred to by the CLOSED-CAPTIONS
+	// attribute, or nil.
+	ClosedCaptions *RenditionGroup
+}
+
+// Group returns the group of type t, or nil if the Variant Stream does not
+// refer to one.
+func (vr *VariantRenditions) Group(t MediaType) *RenditionGroup {
+	switch t {
+	case Audio:
+		return vr.Audio
+	case Video:
+		return vr.Video
+	case Subtitles:
+		return vr.Subtitles
+	case ClosedCaptions:
+		return vr.ClosedCaptions
+	}
+
+	return nil
+}
+
+func (vr *VariantRenditions) setGroup(group *RenditionGroup) {
+	switch group.Type {
+	case Audio:
+		vr.Audio = group
+	case Video:
+		vr.Video = group
+	case Subtitles:
+		vr.Subtitles = group
+	case ClosedCaptions:
+		vr.ClosedCaptions = group
+	}
+}
+
+// DanglingGroupError is reported when a Variant Stream or I-frame Stream
+// refers to a group of Renditions that does not exist.
+type DanglingGroupError struct {
+	// URI identifies the stream that refers to the group.
+	URI string
+
+	Type    MediaType
+	GroupID string
+}
+
+func (e *DanglingGroupError) Error() string {
+	return `m3u8: stream, "` + e.URI + `", refers to undefined ` + e.Type.String() + ` group, "` + e.GroupID + `",`
+}
+
+// UnusedGroupError is reported when no Variant Stream or I-frame Stream
+// refers to a group of Renditions.
+type UnusedGroupError struct {
+	Type    MediaType
+	GroupID string
+}
+
+func (e *UnusedGroupError) Error() string {
+	return `m3u8: ` + e.Type.String() + ` group, "` + e.GroupID + `", is not used by any stream`
+}
+
+// ErrorList is a list of errors that are reported together.
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "m3u8: no errors"
+	case 1:
+		return l[0].Error()
+	}
+
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = strings.TrimPrefix(err.Error(), "m3u8: ")
+	}
+
+	return "m3u8: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors in the list.
+func (l ErrorList) Unwrap() []error {
+	return l
+}
+
+// err returns nil if the list is empty, and the list otherwise.
+func (l ErrorList) err() error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	return l
+}
+
+type groupKey struct {
+	t  MediaType
+	id string
+}
+
+// RenditionGroups returns the groups of Renditions in the Master Playlist, in
+// the order in which they first appear.
+func (p *MasterPlaylist) RenditionGroups() []*RenditionGroup {
+	groups, _ := p.renditionGroups()
+	return groups
+}
+
+func (p *MasterPlaylist) renditionGroups() ([]*RenditionGroup, map[groupKey]*RenditionGroup) {
+	var groups []*RenditionGroup
+	byKey := map[groupKey]*RenditionGroup{}
+	for _, r := range p.RenditionMap {
+		key := groupKey{r.Type(), r.groupID()}
+		group, ok := byKey[key]
+		if !ok {
+			group = &RenditionGroup{Type: key.t, ID: key.id}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+
+		group.Renditions = append(group.Renditions, r)
+
+		if r.isDefault() && group.Default == nil {
+			group.Default = r
+		}
+
+		if r.isAutoSelect() {
+			group.AutoSelect = append(group.AutoSelect, r)
+		}
+	}
+
+	return groups, byKey
+}
+
+// ResolveRenditions returns the groups of Renditions that each Variant Stream
+// refers to, in the order of VariantStreams.
+//
+// If a stream refers to a group that does not exist, or a group is not
+// referred to by any stream, the resolved Variant Streams are returned along
+// with an ErrorList of *DanglingGroupError and *UnusedGroupError values.
+func (p *MasterPlaylist) ResolveRenditions() ([]*VariantRenditions, error) {
+	groups, byKey := p.renditionGroups()
+
+	var errs ErrorList
+	used := map[*RenditionGroup]bool{}
+	resolve := func(uri string, t MediaType, groupID string) *RenditionGroup {
+		if groupID == "" {
+			return nil
+		}
+
+		group, ok := byKey[groupKey{t, groupID}]
+		if !ok {
+			errs = append(errs, &DanglingGroupError{URI: uri, Type: t, GroupID: groupID})
+			return nil
+		}
+
+		used[group] = true
+
+		return group
+	}
+
+	resolved := make([]*VariantRenditions, len(p.VariantStreams))
+	for i, vs := range p.VariantStreams {
+		vr := VariantRenditions{Variant: vs}
+		for _, ga := range groupAttrs {
+			if group := resolve(vs.URI, ga.t, vs.Group(ga.t)); group != nil {
+				vr.setGroup(group)
+			}
+		}
+
+		resolved[i] = &vr
+	}
+
+	for _, s := range p.IFrameStreams {
+		resolve(s.URI, Video, s.VideoGroupID)
+	}
+
+	for _, group := range groups {
+		if !used[group] {
+			errs = append(errs, &UnusedGroupError{Type: group.Type, GroupID: group.ID})
+		}
+	}
+
+	return resolved, errs.err()
+}
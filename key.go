@@ -14,13 +14,22 @@ type Key struct {
 	// Method is REQUIRED.
 	Method EncryptionMethod
 
+	// MethodName is the name of the encryption method if Method is
+	// UnknownEncryption.
+	MethodName string
+
 	// URI specifies how to obtain the key.
 	//
-	// URI is REQUIRED unless Method is NoEncryption
+	// URI is REQUIRED unless Method is NoEncryption, in which case it MUST
+	// NOT be present.
 	URI string
 
 	// IV specifies a 128-bit unsigned integer Initialization Vector to be used
-	// with the key.
+	// with the key, in big-endian byte order.
+	//
+	// IV is OPTIONAL; a nil value indicates that the Media Sequence Number of
+	// each Media Segment is to be used as its IV for keys with the "identity"
+	// key format. See MediaPlaylist.EffectiveIV.
 	IV *[16]byte
 
 	// KeyFormat specifies how the key is represented in the resource
@@ -40,7 +49,10 @@ type Key struct {
 	KeyFormatVersions []uint
 }
 
-func parseKey(version int, meta string) (*Key, error) {
+// identityKeyFormat is the implicit KeyFormat value of keys without one.
+const identityKeyFormat = "identity"
+
+func parseKey(version int, strict bool, meta string) (*Key, error) {
 	attrs, err := parseAttributeList(meta)
 	if err != nil {
 		return nil, err
@@ -55,7 +67,11 @@ func parseKey(version int, meta string) (*Key, error) {
 
 	k.Method, err = ParseEncryptionMethod(method)
 	if err != nil {
-		return nil, &invalidAttributeValueError{attrMethod}
+		if strict {
+			return nil, &invalidAttributeValueError{attrMethod}
+		}
+
+		k.Method, k.MethodName = UnknownEncryption, method
 	}
 
 	k.URI, err = attrs.string(attrURI)
@@ -69,9 +85,16 @@ func parseKey(version int, meta string) (*Key, error) {
 		return nil, err
 	}
 
-	var b [16]byte
-	copy(b[:], iv)
-	k.IV = &b
+	if iv != nil {
+		if len(iv) > 16 {
+			return nil, &invalidAttributeValueError{attrIV}
+		}
+
+		// shorter hexadecimal sequences represent the same 128-bit integer
+		var b [16]byte
+		copy(b[16-len(iv):], iv)
+		k.IV = &b
+	}
 
 	k.KeyFormat, err = attrs.string(attrKeyFormat)
 	if missing := isMissingAttr(err); err != nil && !missing {
@@ -96,13 +119,65 @@ func parseKey(version int, meta string) (*Key, error) {
 		}
 	}
 
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+
 	return &k, nil
 }
 
+// validate checks the rules that are specific to the encryption method of the
+// key.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.4.4.
+func (k *Key) validate() error {
+	switch k.Method {
+	case NoEncryption:
+		if k.URI != "" || k.IV != nil || k.KeyFormat != "" || len(k.KeyFormatVersions) > 0 {
+			return &Error{"keys with method " + NoEncryption.String() + " must not have other attributes"}
+		}
+
+		return nil
+
+	case UnknownEncryption:
+		if k.MethodName == "" {
+			return ErrBadEncryptionMethod
+		}
+
+	case AES128, SampleAES, SampleAESCTR:
+
+	default:
+		return ErrBadEncryptionMethod
+
+	}
+
+	if k.URI == "" {
+		return &missingRequiredAttrError{attrURI}
+	}
+
+	return nil
+}
+
+// method returns the name of the encryption method.
+func (k *Key) method() string {
+	if k.Method == UnknownEncryption {
+		return k.MethodName
+	}
+
+	return k.Method.String()
+}
+
 func (k *Key) attrs() (attributes, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+
 	attrs := attributes{
-		attrMethod: enumeratedString(k.Method.String()),
-		attrURI:    k.URI,
+		attrMethod: enumeratedString(k.method()),
+	}
+
+	if k.URI != "" {
+		attrs[attrURI] = k.URI
 	}
 
 	if k.IV != nil {
@@ -124,3 +199,67 @@ func (k *Key) attrs() (attributes, error) {
 
 	return attrs, nil
 }
+
+// keyFormat returns the KeyFormat value, or its implicit value if empty.
+func (k *Key) keyFormat() string {
+	if k.KeyFormat == "" {
+		return identityKeyFormat
+	}
+
+	return k.KeyFormat
+}
+
+func (k *Key) equal(other *Key) bool {
+	if k == nil || other == nil {
+		return k == other
+	}
+
+	if k.method() != other.method() || k.URI != other.URI || k.keyFormat() != other.keyFormat() {
+		return false
+	}
+
+	if (k.IV == nil) != (other.IV == nil) || k.IV != nil && *k.IV != *other.IV {
+		return false
+	}
+
+	if len(k.KeyFormatVersions) != len(other.KeyFormatVersions) {
+		return false
+	}
+
+	for i, v := range k.KeyFormatVersions {
+		if v != other.KeyFormatVersions[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkKey returns an error if key conflicts with keys, the other keys of the
+// same Media Segment. Keys with the same key format conflict unless they are
+// equal, and a key with Method NoEncryption conflicts with any other key.
+func checkKey(keys []*Key, key *Key) error {
+	for _, k := range keys {
+		if (k.Method == NoEncryption) != (key.Method == NoEncryption) {
+			return &Error{"keys with method " + NoEncryption.String() + " must not be combined with other keys"}
+		}
+
+		if k.keyFormat() == key.keyFormat() && !k.equal(key) {
+			return &Error{`conflicting keys with key format, "` + key.keyFormat() + `"`}
+		}
+	}
+
+	return nil
+}
+
+// validateKeys returns an error if any of the keys of a Media Segment
+// conflict with each other.
+func validateKeys(keys []*Key) error {
+	for i, key := range keys {
+		if err := checkKey(keys[:i], key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package m3u8
+
+import (
+	"bytes"
+	"io"
+)
+
+// RawLine is a comment or an unknown tag that was retained by a Decoder in
+// lossless mode.
+type RawLine struct {
+	// Text is the content of the line, without the line terminator.
+	Text string
+
+	// Offset is the number of lines of the element that the RawLine belongs to
+	// which precede it, including custom tags. Other raw lines and blank lines
+	// are not counted. For playlists, the header tag is not counted either, so
+	// an Offset of zero places the line right after it.
+	//
+	// The RawLines of an element must be ordered by Offset.
+	Offset int
+}
+
+// lineWriter counts the lines written by the encoder of an element and
+// interleaves the custom tags and raw lines of that element at their offsets.
+type lineWriter struct {
+	w    io.Writer
+	e    *Encoder
+	raws []*RawLine
+	tags []*CustomTag
+
+	// n is the number of lines written, including custom tags, and std is
+	// the number of lines written by the encoder of the element
+	n   int
+	std int
+}
+
+func newLineWriter(w io.Writer, e *Encoder, raws []*RawLine, tags []*CustomTag) *lineWriter {
+	return &lineWriter{
+		w:    w,
+		e:    e,
+		raws: raws,
+		tags: tags,
+	}
+}
+
+// newPlaylistLineWriter returns a lineWriter that does not count the header
+// tag of the playlist.
+func newPlaylistLineWriter(w io.Writer, e *Encoder, raws []*RawLine, tags []*CustomTag) *lineWriter {
+	lw := newLineWriter(w, e, raws, tags)
+	lw.n = -1
+	lw.std = -1
+
+	return lw
+}
+
+func (lw *lineWriter) Write(b []byte) (int, error) {
+	// blank lines are ignored by the decoder, so they are not counted
+	if len(b) > 0 && b[0] != '\n' {
+		if err := lw.flushUntil(lw.std); err != nil {
+			return 0, err
+		}
+
+		lines := bytes.Count(b, []byte{'\n'})
+		lw.n += lines
+		lw.std += lines
+	}
+
+	return lw.w.Write(b)
+}
+
+// flushUntil writes the raw lines and custom tags that precede the next line
+// of the element, given the number of lines that were written by its encoder.
+func (lw *lineWriter) flushUntil(std int) error {
+	for {
+		for len(lw.raws) > 0 && lw.raws[0].Offset <= lw.n {
+			if _, err := io.WriteString(lw.w, lw.raws[0].Text+"\n"); err != nil {
+				return err
+			}
+
+			lw.raws = lw.raws[1:]
+		}
+
+		if len(lw.tags) == 0 || lw.tags[0].Offset > std {
+			return nil
+		}
+
+		line, err := lw.e.tags.encode(lw.tags[0])
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(lw.w, line+"\n"); err != nil {
+			return err
+		}
+
+		lw.tags = lw.tags[1:]
+		lw.n++
+	}
+}
+
+// flush writes the remaining raw lines and custom tags.
+func (lw *lineWriter) flush() error {
+	if err := lw.flushUntil(int(^uint(0) >> 1)); err != nil {
+		return err
+	}
+
+	for _, r := range lw.raws {
+		if _, err := io.WriteString(lw.w, r.Text+"\n"); err != nil {
+			return err
+		}
+	}
+
+	lw.raws = nil
+
+	return nil
+}
+
+// rawLines collects the raw lines that precede the next recognized line of
+// the element being decoded.
+type rawLines []string
+
+// place returns the pending raw lines at the given offset and clears them.
+func (rl *rawLines) place(offset int) []*RawLine {
+	if len(*rl) == 0 {
+		return nil
+	}
+
+	raws := make([]*RawLine, len(*rl))
+	for i, text := range *rl {
+		raws[i] = &RawLine{
+			Text:   text,
+			Offset: offset,
+		}
+	}
+
+	*rl = (*rl)[:0]
+
+	return raws
+}
+
+// Layout records how the elements of a decoded playlist were laid out in the
+// playlist file, such as the lines of their tags and, in lossless mode, the
+// order of their attributes. It is kept apart from the elements so that they
+// remain comparable. Elements that are added to the playlist after decoding
+// have no recorded layout.
+type Layout struct {
+	lines  map[interface{}]int
+	orders map[interface{}]attributeOrder
+}
+
+// newLayout returns an empty Layout, which only records the order of
+// attributes if lossless is true.
+func newLayout(lossless bool) *Layout {
+	l := &Layout{
+		lines: make(map[interface{}]int),
+	}
+
+	if lossless {
+		l.orders = make(map[interface{}]attributeOrder)
+	}
+
+	return l
+}
+
+// Line returns the number of the line of the tag from which the element v was
+// decoded, where the header tag is on line 1, or zero if it is unknown. The
+// element must be given as a pointer, such as a *MediaSegment, for which the
+// line of its EXTINF tag is returned. The line of the EXT-X-PROGRAM-DATE-TIME
+// tag of a Media Segment is returned for a pointer to its ProgramDateTime.
+func (l *Layout) Line(v interface{}) int {
+	if l == nil {
+		return 0
+	}
+
+	return l.lines[v]
+}
+
+// recordLine records the line of the tag from which the element v was
+// decoded. It does nothing if l is nil.
+func (l *Layout) recordLine(v interface{}, s *split) {
+	if l == nil {
+		return
+	}
+
+	l.lines[v] = s.num
+}
+
+// record records the line and, in lossless mode, the attribute order of the
+// tag from which the element v was decoded. It does nothing if l is nil.
+func (l *Layout) record(v interface{}, s *split) {
+	if l == nil {
+		return
+	}
+
+	l.lines[v] = s.num
+	if l.orders == nil {
+		return
+	}
+
+	if _, order, err := parseOrderedAttributeList(s.meta); err == nil {
+		l.orders[v] = order
+	}
+}
+
+// order returns the order of the attributes of the tag from which the
+// element v was decoded, if any.
+func (l *Layout) order(v interface{}) attributeOrder {
+	if l == nil {
+		return nil
+	}
+
+	return l.orders[v]
+}
@@ -12,5 +12,5 @@ const (
 
 type Playlist interface {
 	Type() Type
-	encode(io.Writer) error
+	encode(io.Writer, *Encoder) error
 }
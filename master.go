@@ -40,68 +40,189 @@ type MasterPlaylist struct {
 	//
 	// SessionKeys is OPTIONAL.
 	SessionKeys []*Key
+
+	// ContentSteering allows a server to provide a Content Steering Manifest.
+	//
+	// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.6.6.
+	//
+	// ContentSteering is OPTIONAL.
+	ContentSteering *ContentSteering
+
+	// CustomTags are the tags with a registered TagHandler in the Master
+	// Playlist.
+	CustomTags []*CustomTag
+
+	// RawLines are the comments and unknown tags in the Master Playlist. They
+	// are only retained by a Decoder in lossless mode.
+	RawLines []*RawLine
 }
 
-func parseMasterPlaylist(base *GenericPlaylist, lines []line) (*MasterPlaylist, error) {
+func parseMasterPlaylist(base *GenericPlaylist, lines []line, strict bool, errs *errorCollector) (*MasterPlaylist, error) {
 	var p MasterPlaylist
+
+	// the number of playlist lines after the header tag, with and without
+	// custom tags, and the raw lines that precede the next one
+	var n, std int
+	var pending rawLines
+
 	for i := 0; i < len(lines); i++ {
+		if r, ok := lines[i].(raw); ok {
+			pending = append(pending, string(r))
+			continue
+		}
+
+		if c, ok := lines[i].(*custom); ok {
+			p.RawLines = append(p.RawLines, pending.place(n)...)
+			n++
+
+			c.tag.Offset = std
+			p.CustomTags = append(p.CustomTags, c.tag)
+			continue
+		}
+
 		s, ok := lines[i].(*split)
 		if !ok {
-			return nil, isew(s, ErrUnexpectedURI)
+			return nil, ErrUnexpectedURI
 		}
 
+		p.RawLines = append(p.RawLines, pending.place(n)...)
+		n++
+		std++
+
 		switch s.tag {
 		case mediaTag:
 			rendition, err := parseRendition(s.meta)
 			if err != nil {
-				return nil, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
+			base.Layout.record(rendition.basic(), s)
 			p.RenditionMap = append(p.RenditionMap, rendition)
 
 		case streamInfTag:
+			// raw lines may appear between the tag and its uri
+			for i+1 < len(lines) {
+				r, ok := lines[i+1].(raw)
+				if !ok {
+					break
+				}
+
+				pending = append(pending, string(r))
+				i++
+			}
+
+			if i+1 >= len(lines) {
+				if err := errs.handle(isew(s, ErrMissingURI)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
 			uri, ok := lines[i+1].(uri)
 			if !ok {
-				return nil, isew(s, ErrMissingURI)
+				if err := errs.handle(isew(s, ErrMissingURI)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
+			p.RawLines = append(p.RawLines, pending.place(n)...)
+			n++
+			std++
 			i++
 
-			vs, err := parseVariantStream(s.meta)
+			vs, err := parseVariantStream(base.Version, s.meta)
 			if err != nil {
-				return nil, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
 			vs.URI = string(uri)
+			base.Layout.record(vs, s)
 
 			p.VariantStreams = append(p.VariantStreams, vs)
 
 		case iFrameStreamInfTag:
-			ifs, err := parseIFrameStream(s.meta)
+			ifs, err := parseIFrameStream(base.Version, s.meta)
 			if err != nil {
-				return nil, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
+			base.Layout.record(ifs, s)
 			p.IFrameStreams = append(p.IFrameStreams, ifs)
 
 		case sessionDataTag:
 			sde, err := parseSessionDataEntry(s.meta)
 			if err != nil {
-				return nil, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
+			base.Layout.record(sde, s)
 			p.SessionData = append(p.SessionData, sde)
 
 		case sessionKeyTag:
-			key, err := parseKey(base.Version, s.meta)
+			key, err := parseKey(base.Version, strict, s.meta)
 			if err != nil {
-				return nil, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			if key.Method == NoEncryption {
+				if err := errs.handle(isew(s, ErrSessionKeyNone)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
+			base.Layout.record(key, s)
 			p.SessionKeys = append(p.SessionKeys, key)
+
+		case contentSteeringTag:
+			if p.ContentSteering != nil {
+				if err := errs.handle(ise(s, "content steering is already defined")); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			cs, err := parseContentSteering(s.meta)
+			if err != nil {
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			base.Layout.record(cs, s)
+			p.ContentSteering = cs
 		}
 	}
 
+	p.RawLines = append(p.RawLines, pending.place(n)...)
+
 	p.GenericPlaylist = base
 
 	return &p, nil
@@ -111,11 +232,81 @@ func (*MasterPlaylist) Type() Type {
 	return Master
 }
 
-func (p *MasterPlaylist) encode(w io.Writer) error {
-	if err := p.GenericPlaylist.encode(w); err != nil {
+func (p *MasterPlaylist) encode(out io.Writer, e *Encoder) error {
+	w := newPlaylistLineWriter(out, e, p.RawLines, p.CustomTags)
+	e.layout = p.Layout
+
+	version, err := e.version(p, p.Version)
+	if err != nil {
+		return err
+	}
+
+	if err := p.GenericPlaylist.encode(w, e, version); err != nil {
 		return err
 	}
 
+	if p.ContentSteering != nil {
+		attrs, err := p.ContentSteering.attrs()
+		if err != nil {
+			return err
+		}
+
+		encodedAttrs, err := attrs.encode(e.attributeOrder(contentSteeringTag, p.ContentSteering))
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(w, contentSteeringTag+":"+encodedAttrs); err != nil {
+			return err
+		}
+	}
+
+	if len(p.SessionData) > 0 {
+		if err := p.SessionData.validate(); err != nil {
+			return err
+		}
+
+		for _, sde := range p.SessionData {
+			attrs, err := sde.attrs()
+			if err != nil {
+				return err
+			}
+
+			encodedAttrs, err := attrs.encode(e.attributeOrder(sessionDataTag, sde))
+			if err != nil {
+				return err
+			}
+
+			if _, err := fmt.Fprintln(w, sessionDataTag+":"+encodedAttrs); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, key := range p.SessionKeys {
+		if key.Method == NoEncryption {
+			return ErrSessionKeyNone
+		}
+
+		if key.URI == "" {
+			return &missingRequiredAttrError{attrURI}
+		}
+
+		attrs, err := key.attrs()
+		if err != nil {
+			return err
+		}
+
+		encodedAttrs, err := attrs.encode(e.attributeOrder(sessionKeyTag, key))
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(w, sessionKeyTag+":"+encodedAttrs); err != nil {
+			return err
+		}
+	}
+
 	if len(p.RenditionMap) > 0 {
 		if err := renditions(p.RenditionMap).validate(); err != nil {
 			return err
@@ -127,7 +318,7 @@ func (p *MasterPlaylist) encode(w io.Writer) error {
 				return err
 			}
 
-			encodedAttrs, err := attrs.encode()
+			encodedAttrs, err := attrs.encode(e.attributeOrder(mediaTag, a.basic()))
 			if err != nil {
 				return err
 			}
@@ -147,7 +338,7 @@ func (p *MasterPlaylist) encode(w io.Writer) error {
 				return err
 			}
 
-			encodedAttrs, err := attrs.encode()
+			encodedAttrs, err := attrs.encode(e.attributeOrder(streamInfTag, stream))
 			if err != nil {
 				return err
 			}
@@ -166,5 +357,27 @@ func (p *MasterPlaylist) encode(w io.Writer) error {
 		}
 	}
 
-	return nil
+	for _, stream := range p.IFrameStreams {
+		if stream.URI == "" {
+			return &missingRequiredAttrError{attrURI}
+		}
+
+		attrs, err := stream.attrs()
+		if err != nil {
+			return err
+		}
+
+		attrs[attrURI] = stream.URI
+
+		encodedAttrs, err := attrs.encode(e.attributeOrder(iFrameStreamInfTag, stream))
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(w, iFrameStreamInfTag+":"+encodedAttrs); err != nil {
+			return err
+		}
+	}
+
+	return w.flush()
 }
@@ -0,0 +1,52 @@
+package m3u8_test
+
+import (
+	"testing"
+
+	"github.com/ssttevee/m3u8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRenditions(t *testing.T) {
+	const data = "#EXTM3U\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",NAME=\"English\",DEFAULT=YES,AUTOSELECT=YES,URI=\"en.m3u8\"\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",NAME=\"French\",AUTOSELECT=YES,URI=\"fr.m3u8\"\n#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=\"English\",URI=\"en.vtt.m3u8\"\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"ac3\",NAME=\"English\",URI=\"en-ac3.m3u8\"\n#EXT-X-STREAM-INF:BANDWIDTH=1280000,AUDIO=\"aac\",SUBTITLES=\"subs\"\nlow.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=2560000,AUDIO=\"aac\",SUBTITLES=\"missing\"\nhigh.m3u8\n"
+
+	plist, err := m3u8.DecodePlaylist([]byte(data))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	mplist := plist.(*m3u8.MasterPlaylist)
+
+	assert.Len(t, mplist.RenditionGroups(), 3)
+
+	resolved, err := mplist.ResolveRenditions()
+	if assert.IsType(t, m3u8.ErrorList{}, err) {
+		errs := err.(m3u8.ErrorList)
+		if assert.Len(t, errs, 2) {
+			assert.Equal(t, &m3u8.DanglingGroupError{URI: "high.m3u8", Type: m3u8.Subtitles, GroupID: "missing"}, errs[0])
+			assert.Equal(t, &m3u8.UnusedGroupError{Type: m3u8.Audio, GroupID: "ac3"}, errs[1])
+		}
+	}
+
+	if !assert.Len(t, resolved, 2) {
+		t.FailNow()
+	}
+
+	low := resolved[0]
+	assert.True(t, mplist.VariantStreams[0] == low.Variant)
+	if assert.NotNil(t, low.Audio) {
+		assert.Equal(t, "aac", low.Audio.ID)
+		assert.Len(t, low.Audio.Renditions, 2)
+		assert.True(t, mplist.RenditionMap[0] == low.Audio.Default)
+		assert.Len(t, low.Audio.AutoSelect, 2)
+	}
+
+	if assert.NotNil(t, low.Subtitles) {
+		assert.Equal(t, "subs", low.Subtitles.ID)
+		assert.Nil(t, low.Subtitles.Default)
+	}
+
+	assert.Nil(t, low.Video)
+	assert.True(t, low.Audio == resolved[1].Group(m3u8.Audio))
+	assert.Nil(t, resolved[1].Subtitles)
+}
@@ -1,9 +1,11 @@
 package m3u8
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 	"strconv"
+	"time"
 )
 
 type EncryptionMethod int
@@ -12,6 +14,12 @@ const (
 	NoEncryption EncryptionMethod = iota + 1
 	AES128
 	SampleAES
+	SampleAESCTR
+
+	// UnknownEncryption is the method of keys with an encryption method that
+	// is not known to this package, which are only decoded in non-strict
+	// mode. The name of the method is retained as the MethodName of the Key.
+	UnknownEncryption
 )
 
 func (m EncryptionMethod) String() string {
@@ -22,9 +30,11 @@ func (m EncryptionMethod) String() string {
 		return "AES-128"
 	case SampleAES:
 		return "SAMPLE-AES"
+	case SampleAESCTR:
+		return "SAMPLE-AES-CTR"
 	}
 
-	panic("invalid encryption method")
+	return "EncryptionMethod(" + strconv.Itoa(int(m)) + ")"
 }
 
 func ParseEncryptionMethod(str string) (EncryptionMethod, error) {
@@ -35,6 +45,8 @@ func ParseEncryptionMethod(str string) (EncryptionMethod, error) {
 		return AES128, nil
 	case "SAMPLE-AES":
 		return SampleAES, nil
+	case "SAMPLE-AES-CTR":
+		return SampleAESCTR, nil
 	}
 
 	return 0, ErrBadEncryptionMethod
@@ -53,12 +65,24 @@ func (m *Map) attrs() (attributes, error) {
 	}
 
 	if m.ByteRange != nil {
-		attrs[byterangeTag] = m.ByteRange.String()
+		attrs[attrByteRange] = m.ByteRange.String()
 	}
 
 	return attrs, nil
 }
 
+func (m *Map) equal(other *Map) bool {
+	if m == nil || other == nil {
+		return m == other
+	}
+
+	if (m.ByteRange == nil) != (other.ByteRange == nil) || m.ByteRange != nil && *m.ByteRange != *other.ByteRange {
+		return false
+	}
+
+	return m.URI == other.URI
+}
+
 type PlaylistType int
 
 const (
@@ -121,71 +145,316 @@ type MediaPlaylist struct {
 	//
 	// See https://tools.ietf.org/html/rfc8216#section-4.3.3.6.
 	IFramesOnly bool
+
+	// Live indicates that more Media Segments may be added to the Media
+	// Playlist, so the EXT-X-ENDLIST tag is not encoded. It is set by the
+	// Decoder for playlists without an EXT-X-ENDLIST tag.
+	//
+	// See https://tools.ietf.org/html/rfc8216#section-4.3.3.4 and
+	// https://tools.ietf.org/html/rfc8216#section-6.2.2.
+	Live bool
+
+	// PartTarget specifies the maximum Partial Segment duration.
+	//
+	// PartTarget is REQUIRED if the Media Playlist contains Partial Segments.
+	//
+	// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.3.7.
+	PartTarget time.Duration
+
+	// ServerControl allows the server to indicate support for Delivery
+	// Directives.
+	//
+	// ServerControl is OPTIONAL.
+	ServerControl *ServerControl
+
+	// Skip indicates that the Media Playlist is a Playlist Delta Update, in
+	// which Skip.SkippedSegments Media Segments preceding Segments have been
+	// skipped.
+	//
+	// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-6.2.5.1.
+	Skip *Skip
+
+	// Parts are the Partial Segments of the next Media Segment, which is not
+	// yet complete. They appear after the last Media Segment in the Playlist
+	// file.
+	Parts []*PartialSegment
+
+	// PreloadHints allow a client to request resources that will be required
+	// to play the presentation before they are available. There MUST NOT be
+	// more than one PreloadHint with the same Type.
+	//
+	// PreloadHints is OPTIONAL.
+	PreloadHints PreloadHints
+
+	// RenditionReports carry information about associated Renditions that is
+	// as up-to-date as the Media Playlist.
+	//
+	// RenditionReports is OPTIONAL.
+	RenditionReports []*RenditionReport
+
+	// CustomTags are the tags with a registered TagHandler that do not belong
+	// to a Media Segment.
+	CustomTags []*CustomTag
+
+	// RawLines are the comments and unknown tags that do not belong to a Media
+	// Segment. They are only retained by a Decoder in lossless mode.
+	RawLines []*RawLine
 }
 
-func parseMediaPlaylist(base *GenericPlaylist, lines []line) (_ *MediaPlaylist, err error) {
-	var p MediaPlaylist
-	var endlist bool
+func parseMediaPlaylist(base *GenericPlaylist, lines []line, strict bool, errs *errorCollector) (_ *MediaPlaylist, err error) {
+	// the playlist is live until an EXT-X-ENDLIST tag is found
+	p := MediaPlaylist{GenericPlaylist: base, Live: true}
+	var partInf, serverControl *split
+
+	// the number of playlist lines after the header tag, with and without
+	// custom tags, and the raw lines that precede the next one
+	var n, std int
+	var pending rawLines
+
 	for i := 0; i < len(lines); i++ {
-		if skip, err := parseMediaSegment(&p, base.Version, lines[i:]); err != nil && err != ErrNotASegment {
+		if r, ok := lines[i].(raw); ok {
+			pending = append(pending, string(r))
+			continue
+		}
+
+		if skip, err := parseMediaSegment(&p, base.Version, strict, lines[i:], errs); err != nil && err != ErrNotASegment {
 			return nil, err
 		} else if err == nil {
-			if endlist {
-				return nil, ErrUnexpectedMediaSegment
+			if !p.Live {
+				s, ok := lines[i].(*split)
+				if !ok {
+					s = lines[i].(*custom).split
+				}
+
+				if err := errs.handle(isew(s, ErrUnexpectedMediaSegment)); err != nil {
+					return nil, err
+				}
+			}
+
+			if raws := pending.place(0); raws != nil {
+				last := p.last()
+				last.RawLines = append(raws, last.RawLines...)
 			}
 
 			i += skip
 			continue
 		}
 
+		p.RawLines = append(p.RawLines, pending.place(n)...)
+		n++
+
+		if c, ok := lines[i].(*custom); ok {
+			c.tag.Offset = std
+			p.CustomTags = append(p.CustomTags, c.tag)
+			continue
+		}
+
 		s := lines[i].(*split)
+		std++
 
 		switch s.tag {
 		case targetdurationTag:
 			if !rxDecimalInteger.MatchString(s.meta) {
-				return nil, isew(s, ErrBadSyntax)
+				if err := errs.handle(isew(s, ErrBadSyntax)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
 			p.TargetDuration, _ = strconv.ParseUint(s.meta, 10, 64)
 
 		case mediaSequenceTag:
 			if len(p.Segments) > 0 {
-				return nil, ise(s, "this tag must appear before the first media segment")
+				if err := errs.handle(ise(s, "this tag must appear before the first media segment")); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
 			if !rxDecimalInteger.MatchString(s.meta) {
-				return nil, isew(s, ErrBadSyntax)
+				if err := errs.handle(isew(s, ErrBadSyntax)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
 			p.MediaSequence, _ = strconv.ParseUint(s.meta, 10, 64)
 
 		case discontinuitySequenceTag:
 			if len(p.Segments) > 0 {
-				return nil, ise(s, "this tag must appear before the first media segment")
+				if err := errs.handle(ise(s, "this tag must appear before the first media segment")); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
 			if !rxDecimalInteger.MatchString(s.meta) {
-				return nil, isew(s, ErrBadSyntax)
+				if err := errs.handle(isew(s, ErrBadSyntax)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
 			p.DiscontinuousSequence, _ = strconv.ParseUint(s.meta, 10, 64)
 
 		case endlistTag:
-			endlist = true
+			p.Live = false
 
 		case playlistTypeTag:
 			p.PlaylistType, err = ParsePlaylistType(s.meta)
 			if err != nil {
-				return nil, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
 		case iFramesOnlyTag:
 			p.IFramesOnly = true
 
+		case partInfTag:
+			attrs, err := parseAttributeList(s.meta)
+			if err != nil {
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			partTarget, err := attrs.float(attrPartTarget)
+			if err != nil {
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			p.PartTarget = secondsToDuration(partTarget)
+			partInf = s
+
+		case partTag:
+			part, err := parsePartialSegment(s.meta)
+			if err != nil {
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			if !part.continues(p.lastPart()) {
+				if err := errs.handle(isew(s, ErrNoRangeStart)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			base.Layout.record(part, s)
+			p.Parts = append(p.Parts, part)
+
+		case serverControlTag:
+			p.ServerControl, err = parseServerControl(s.meta)
+			if err != nil {
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			base.Layout.record(p.ServerControl, s)
+			serverControl = s
+
+		case skipTag:
+			if len(p.Segments) > 0 {
+				if err := errs.handle(ise(s, "this tag must appear before the first media segment")); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			p.Skip, err = parseSkip(base.Version, s.meta)
+			if err != nil {
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			base.Layout.record(p.Skip, s)
+
+		case preloadHintTag:
+			hint, err := parsePreloadHint(s.meta)
+			if err != nil {
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			if p.PreloadHints.Hint(hint.Type) != nil {
+				if err := errs.handle(ise(s, "this tag must not appear more than once with the same type")); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			base.Layout.record(hint, s)
+			p.PreloadHints = append(p.PreloadHints, hint)
+
+		case renditionReportTag:
+			report, err := parseRenditionReport(s.meta)
+			if err != nil {
+				if err := errs.handle(isew(s, err)); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			base.Layout.record(report, s)
+			p.RenditionReports = append(p.RenditionReports, report)
+
+		}
+	}
+
+	p.RawLines = append(p.RawLines, pending.place(n)...)
+
+	if partInf == nil && p.hasParts() {
+		// the error is reported on the line of the first partial segment
+		var part *split
+		for _, line := range lines {
+			if s, ok := line.(*split); ok && s.tag == partTag {
+				part = s
+				break
+			}
+		}
+
+		if err := errs.handle(ise(part, "missing "+partInfTag+" tag")); err != nil {
+			return nil, err
 		}
 	}
 
-	p.GenericPlaylist = base
+	if p.ServerControl != nil {
+		if err := p.ServerControl.validate(p.TargetDuration, p.PartTarget); err != nil {
+			if err := errs.handle(isew(serverControl, err)); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	return &p, nil
 }
@@ -198,12 +467,240 @@ func (p *MediaPlaylist) last() *MediaSegment {
 	return nil
 }
 
+// firstSequence returns the Media Sequence Number of the first Media Segment
+// in Segments, which follows the skipped segments of a Playlist Delta Update.
+func (p *MediaPlaylist) firstSequence() uint64 {
+	if p.Skip != nil {
+		return p.MediaSequence + p.Skip.SkippedSegments
+	}
+
+	return p.MediaSequence
+}
+
+// EffectiveBitrate returns the approximate bit rate, in kilobits per second,
+// of the Media Segment at index i of Segments, as specified by the nearest
+// preceding non-zero Bitrate value.
+//
+// EffectiveBitrate returns zero if the Media Segment has a ByteRange value or
+// if no bit rate applies to it.
+func (p *MediaPlaylist) EffectiveBitrate(i int) uint64 {
+	if p.Segments[i].ByteRange != nil {
+		return 0
+	}
+
+	for ; i >= 0; i-- {
+		if bitrate := p.Segments[i].Bitrate; bitrate > 0 {
+			return bitrate
+		}
+	}
+
+	return 0
+}
+
+// EffectiveKeys returns the keys that apply to the Media Segment at index i
+// of Segments, which are the nearest preceding keys with distinct key
+// formats, in the order in which the key formats first appeared.
+//
+// EffectiveKeys returns nil if the Media Segment is not encrypted, which is
+// also the case after a Key with Method NoEncryption.
+//
+// EffectiveKeys replays the keys of every Media Segment up to index i, so
+// AllEffectiveKeys should be used to obtain the keys of many Media Segments.
+//
+// See https://tools.ietf.org/html/rfc8216#section-4.3.2.4.
+func (p *MediaPlaylist) EffectiveKeys(i int) []*Key {
+	var keys []*Key
+	for _, segment := range p.Segments[:i+1] {
+		for _, key := range segment.keys() {
+			keys = applyKey(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// AllEffectiveKeys returns the keys that apply to each Media Segment of
+// Segments, as returned by EffectiveKeys, in a single pass over the Media
+// Segments. Consecutive Media Segments with the same effective keys share the
+// same slice, which must not be modified.
+func (p *MediaPlaylist) AllEffectiveKeys() [][]*Key {
+	all := make([][]*Key, len(p.Segments))
+
+	var keys []*Key
+	for i, segment := range p.Segments {
+		for _, key := range segment.keys() {
+			keys = applyKey(keys, key)
+		}
+
+		all[i] = keys
+	}
+
+	return all
+}
+
+// applyKey returns the effective keys after key.
+func applyKey(keys []*Key, key *Key) []*Key {
+	if key.Method == NoEncryption {
+		return nil
+	}
+
+	for i, k := range keys {
+		if k.keyFormat() == key.keyFormat() {
+			keys = append([]*Key(nil), keys...)
+			keys[i] = key
+			return keys
+		}
+	}
+
+	return append(keys[:len(keys):len(keys)], key)
+}
+
+// isEffective reports whether key is already in effect, given the effective
+// keys.
+func isEffective(keys []*Key, key *Key) bool {
+	if key.Method == NoEncryption {
+		return len(keys) == 0
+	}
+
+	for _, k := range keys {
+		if k.equal(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EffectiveIV returns the Initialization Vector to be used to decrypt the
+// Media Segment at index i of Segments with the effective key with the given
+// key format, where an empty key format indicates "identity".
+//
+// The IV of the key is used if it has one. Otherwise, the Media Sequence
+// Number of the Media Segment is used if the key has the "identity" key
+// format and uses the AES128 or SampleAES method. Keys of other key formats
+// are interpreted by their key systems, and other methods, such as
+// SampleAESCTR, carry their IVs in the Media Segments instead. If no such IV
+// applies to the Media Segment, ok is false.
+//
+// See https://tools.ietf.org/html/rfc8216#section-5.2.
+func (p *MediaPlaylist) EffectiveIV(i int, keyFormat string) (iv [16]byte, ok bool) {
+	return p.effectiveIV(i, p.EffectiveKeys(i), keyFormat)
+}
+
+// effectiveIV returns the IV of the Media Segment at index i of Segments,
+// given its effective keys.
+func (p *MediaPlaylist) effectiveIV(i int, keys []*Key, keyFormat string) (iv [16]byte, ok bool) {
+	if keyFormat == "" {
+		keyFormat = identityKeyFormat
+	}
+
+	for _, key := range keys {
+		if key.keyFormat() != keyFormat {
+			continue
+		}
+
+		if key.IV != nil {
+			return *key.IV, true
+		}
+
+		if keyFormat != identityKeyFormat || key.Method != AES128 && key.Method != SampleAES {
+			break
+		}
+
+		binary.BigEndian.PutUint64(iv[8:], p.firstSequence()+uint64(i))
+		return iv, true
+	}
+
+	return iv, false
+}
+
+// EffectiveMap returns the Media Initialization Section of the Media Segment
+// at index i of Segments, as specified by the nearest preceding non-nil Map
+// value, or nil if there is none.
+func (p *MediaPlaylist) EffectiveMap(i int) *Map {
+	for ; i >= 0; i-- {
+		if m := p.Segments[i].Map; m != nil {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// withoutRepeats returns the Media Segment at index i of Segments without
+// the Keys and Map values that are already in effect, given the keys in
+// effect for the previous Media Segment.
+func (p *MediaPlaylist) withoutRepeats(i int, keys []*Key) *MediaSegment {
+	segment := p.Segments[i]
+
+	var newKeys []*Key
+	for _, key := range segment.keys() {
+		if !isEffective(keys, key) {
+			newKeys = append(newKeys, key)
+		}
+	}
+
+	repeatedKeys := len(newKeys) < len(segment.keys())
+	repeatedMap := segment.Map != nil && i > 0 && segment.Map.equal(p.EffectiveMap(i-1))
+	if !repeatedKeys && !repeatedMap {
+		return segment
+	}
+
+	s := *segment
+	if repeatedKeys {
+		s.Keys = newKeys
+		s.Key = nil
+	}
+
+	if repeatedMap {
+		s.Map = nil
+	}
+
+	return &s
+}
+
+func (p *MediaPlaylist) lastPart() *PartialSegment {
+	if part := lastPart(p.Parts); part != nil {
+		return part
+	}
+
+	if last := p.last(); last != nil {
+		return lastPart(last.Parts)
+	}
+
+	return nil
+}
+
+func (p *MediaPlaylist) hasParts() bool {
+	if len(p.Parts) > 0 {
+		return true
+	}
+
+	for _, segment := range p.Segments {
+		if len(segment.Parts) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (*MediaPlaylist) Type() Type {
 	return Media
 }
 
-func (p *MediaPlaylist) encode(w io.Writer) error {
-	if err := p.GenericPlaylist.encode(w); err != nil {
+func (p *MediaPlaylist) encode(out io.Writer, e *Encoder) error {
+	// segments are written directly to out since their lines do not count
+	// towards the offsets of the custom tags and raw lines of the playlist
+	w := newPlaylistLineWriter(out, e, p.RawLines, p.CustomTags)
+	e.layout = p.Layout
+
+	version, err := e.version(p, p.Version)
+	if err != nil {
+		return err
+	}
+
+	if err := p.GenericPlaylist.encode(w, e, version); err != nil {
 		return err
 	}
 
@@ -211,6 +708,34 @@ func (p *MediaPlaylist) encode(w io.Writer) error {
 		return err
 	}
 
+	if p.ServerControl != nil {
+		if err := p.ServerControl.validate(p.TargetDuration, p.PartTarget); err != nil {
+			return err
+		}
+
+		attrs, err := p.ServerControl.attrs()
+		if err != nil {
+			return err
+		}
+
+		encodedAttrs, err := attrs.encode(e.attributeOrder(serverControlTag, p.ServerControl))
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(w, serverControlTag+":"+encodedAttrs); err != nil {
+			return err
+		}
+	}
+
+	if p.PartTarget > 0 {
+		if _, err := fmt.Fprintf(w, partInfTag+":"+attrPartTarget+"=%g\n", p.PartTarget.Seconds()); err != nil {
+			return err
+		}
+	} else if p.hasParts() {
+		return &Error{"missing part target"}
+	}
+
 	if p.MediaSequence > 0 {
 		if _, err := fmt.Fprintf(w, mediaSequenceTag+":%d\n", p.MediaSequence); err != nil {
 			return err
@@ -235,19 +760,87 @@ func (p *MediaPlaylist) encode(w io.Writer) error {
 		}
 	}
 
+	if p.Skip != nil {
+		attrs, err := p.Skip.attrs()
+		if err != nil {
+			return err
+		}
+
+		encodedAttrs, err := attrs.encode(e.attributeOrder(skipTag, p.Skip))
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(w, skipTag+":"+encodedAttrs); err != nil {
+			return err
+		}
+	}
+
 	if len(p.Segments) > 0 {
 		// TODO validate segments
 
-		for _, stream := range p.Segments {
-			if err := stream.encode(w); err != nil {
+		var keys []*Key
+		for i, segment := range p.Segments {
+			if e.OmitRepeatedKeysAndMaps {
+				segment = p.withoutRepeats(i, keys)
+				for _, key := range p.Segments[i].keys() {
+					keys = applyKey(keys, key)
+				}
+			}
+
+			if err := segment.encode(out, e); err != nil {
 				return err
 			}
 		}
 	}
 
-	if _, err := fmt.Fprintln(w, endlistTag); err != nil {
+	if err := encodeParts(w, e, p.Parts); err != nil {
 		return err
 	}
 
-	return nil
+	if len(p.PreloadHints) > 0 {
+		if err := p.PreloadHints.validate(); err != nil {
+			return err
+		}
+
+		for _, hint := range p.PreloadHints {
+			attrs, err := hint.attrs()
+			if err != nil {
+				return err
+			}
+
+			encodedAttrs, err := attrs.encode(e.attributeOrder(preloadHintTag, hint))
+			if err != nil {
+				return err
+			}
+
+			if _, err := fmt.Fprintln(w, preloadHintTag+":"+encodedAttrs); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, report := range p.RenditionReports {
+		attrs, err := report.attrs()
+		if err != nil {
+			return err
+		}
+
+		encodedAttrs, err := attrs.encode(e.attributeOrder(renditionReportTag, report))
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(w, renditionReportTag+":"+encodedAttrs); err != nil {
+			return err
+		}
+	}
+
+	if !p.Live {
+		if _, err := fmt.Fprintln(w, endlistTag); err != nil {
+			return err
+		}
+	}
+
+	return w.flush()
 }
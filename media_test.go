@@ -0,0 +1,178 @@
+package m3u8_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ssttevee/m3u8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaylistDeltaUpdate(t *testing.T) {
+	const data = "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-VERSION:9\n#EXT-X-SERVER-CONTROL:CAN-SKIP-UNTIL=24\n#EXT-X-MEDIA-SEQUENCE:100\n#EXTINF:4,\n100.ts\n#EXTINF:4,\n101.ts\n#EXTINF:4,\n102.ts\n#EXTINF:4,\n103.ts\n#EXTINF:4,\n104.ts\n#EXTINF:4,\n105.ts\n#EXTINF:4,\n106.ts\n#EXTINF:4,\n107.ts\n#EXTINF:4,\n108.ts\n"
+
+	plist, err := m3u8.DecodePlaylist([]byte(data))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	full := plist.(*m3u8.MediaPlaylist)
+
+	delta, err := full.Delta(false)
+	if !assert.Nil(t, err, "should create a delta update") {
+		t.FailNow()
+	}
+
+	if assert.NotNil(t, delta.Skip) {
+		assert.Equal(t, uint64(3), delta.Skip.SkippedSegments)
+	}
+
+	if assert.Len(t, delta.Segments, 6) {
+		assert.Equal(t, "103.ts", delta.Segments[0].URI)
+	}
+
+	_, err = full.Delta(true)
+	assert.NotNil(t, err, "should not skip date ranges without permission")
+
+	var buf bytes.Buffer
+	if !assert.Nil(t, m3u8.NewEncoder(&buf).Encode(delta), "should successfully encode") {
+		t.FailNow()
+	}
+
+	plist, err = m3u8.DecodePlaylist(buf.Bytes())
+	if !assert.Nil(t, err, "should sucessfully parse the delta update") {
+		t.FailNow()
+	}
+
+	merged, err := full.ApplyDelta(plist.(*m3u8.MediaPlaylist))
+	if !assert.Nil(t, err, "should apply the delta update") {
+		t.FailNow()
+	}
+
+	assert.Nil(t, merged.Skip)
+	assert.Equal(t, uint64(100), merged.MediaSequence)
+	if assert.Len(t, merged.Segments, len(full.Segments)) {
+		for i, segment := range merged.Segments {
+			assert.Equal(t, full.Segments[i].URI, segment.URI)
+		}
+	}
+}
+
+func TestPlaylistDeltaUpdateKeyRotation(t *testing.T) {
+	const data = "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-VERSION:9\n#EXT-X-SERVER-CONTROL:CAN-SKIP-UNTIL=24\n#EXT-X-MEDIA-SEQUENCE:100\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n#EXTINF:4,\n100.mp4\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"b.key\"\n#EXTINF:4,\n101.mp4\n" +
+		"#EXTINF:4,\n102.mp4\n" +
+		"#EXTINF:4,\n103.mp4\n#EXTINF:4,\n104.mp4\n#EXTINF:4,\n105.mp4\n#EXTINF:4,\n106.mp4\n#EXTINF:4,\n107.mp4\n#EXTINF:4,\n108.mp4\n"
+
+	plist, err := m3u8.DecodePlaylist([]byte(data))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	delta, err := plist.(*m3u8.MediaPlaylist).Delta(false)
+	if !assert.Nil(t, err, "should create a delta update") {
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+	if !assert.Nil(t, m3u8.NewEncoder(&buf).Encode(delta), "should successfully encode") {
+		t.FailNow()
+	}
+
+	// the key that was rotated within the skipped segments applies to the
+	// remaining segments
+	assert.Contains(t, buf.String(), "URI=\"b.key\"")
+	assert.NotContains(t, buf.String(), "URI=\"a.key\"")
+}
+
+func TestEffectiveKeysAndMaps(t *testing.T) {
+	const data = "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:4\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n#EXTINF:4,\n0.mp4\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"skd://b\",KEYFORMAT=\"com.apple.streamingkeydelivery\"\n#EXTINF:4,\n1.mp4\n#EXT-X-MAP:URI=\"init.mp4\",BYTERANGE=\"720@0\"\n#EXT-X-KEY:METHOD=AES-128,URI=\"c.key\"\n#EXTINF:4,\n2.mp4\n#EXT-X-KEY:METHOD=NONE\n#EXTINF:4,\n3.mp4\n"
+
+	plist, err := m3u8.DecodePlaylist([]byte(data))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	p := plist.(*m3u8.MediaPlaylist)
+
+	uris := func(keys []*m3u8.Key) []string {
+		var uris []string
+		for _, key := range keys {
+			uris = append(uris, key.URI)
+		}
+
+		return uris
+	}
+
+	assert.Equal(t, []string{"a.key"}, uris(p.EffectiveKeys(0)))
+	assert.Equal(t, []string{"a.key", "skd://b"}, uris(p.EffectiveKeys(1)))
+	assert.Equal(t, []string{"c.key", "skd://b"}, uris(p.EffectiveKeys(2)))
+	assert.Nil(t, p.EffectiveKeys(3))
+
+	all := p.AllEffectiveKeys()
+	if assert.Len(t, all, len(p.Segments)) {
+		for i, keys := range all {
+			assert.Equal(t, p.EffectiveKeys(i), keys)
+		}
+	}
+
+	if m := p.EffectiveMap(1); assert.NotNil(t, m) {
+		assert.Equal(t, "init.mp4", m.URI)
+		assert.Nil(t, m.ByteRange)
+	}
+
+	if m := p.EffectiveMap(3); assert.NotNil(t, m) {
+		assert.Equal(t, &m3u8.ByteRange{Start: 0, Length: 720}, m.ByteRange)
+	}
+
+	// a map byte range without an offset starts at the beginning of the
+	// resource
+	plist, err = m3u8.DecodePlaylist([]byte("#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:4\n#EXT-X-MAP:URI=\"init.mp4\",BYTERANGE=\"720\"\n#EXTINF:4,\n0.mp4\n"))
+	if assert.Nil(t, err, "should sucessfully parse") {
+		if m := plist.(*m3u8.MediaPlaylist).EffectiveMap(0); assert.NotNil(t, m) {
+			assert.Equal(t, &m3u8.ByteRange{Start: 0, Length: 720}, m.ByteRange)
+		}
+	}
+}
+
+func TestEffectiveIV(t *testing.T) {
+	const data = "#EXTM3U\n#EXT-X-VERSION:5\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:258\n#EXTINF:4,\n0.ts\n#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"skd://b\",KEYFORMAT=\"com.apple.streamingkeydelivery\",IV=0x1\n#EXTINF:4,\n1.ts\n#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\",IV=0x00000000000000000000000000000000\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"skd://c\",KEYFORMAT=\"com.apple.streamingkeydelivery\"\n#EXTINF:4,\n2.ts\n"
+
+	plist, err := m3u8.DecodePlaylist([]byte(data))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	p := plist.(*m3u8.MediaPlaylist)
+
+	if assert.Len(t, p.Segments[1].Keys, 2) {
+		assert.Nil(t, p.Segments[1].Keys[0].IV)
+	}
+
+	_, ok := p.EffectiveIV(0, "")
+	assert.False(t, ok)
+
+	iv, ok := p.EffectiveIV(1, "identity")
+	assert.True(t, ok)
+	assert.Equal(t, [16]byte{14: 1, 15: 3}, iv)
+
+	iv, ok = p.EffectiveIV(1, "com.apple.streamingkeydelivery")
+	assert.True(t, ok)
+	assert.Equal(t, [16]byte{15: 1}, iv)
+
+	iv, ok = p.EffectiveIV(2, "")
+	assert.True(t, ok)
+	assert.Equal(t, [16]byte{}, iv)
+
+	// the media sequence number is only used by keys of the identity key
+	// format
+	_, ok = p.EffectiveIV(2, "com.apple.streamingkeydelivery")
+	assert.False(t, ok)
+
+	var buf bytes.Buffer
+	if assert.Nil(t, m3u8.NewEncoder(&buf).Encode(p), "should successfully encode") {
+		assert.Contains(t, buf.String(), "#EXT-X-KEY:METHOD=AES-128,URI=\"a.key\"\n")
+		assert.Contains(t, buf.String(), "IV=0x00000000000000000000000000000001,")
+	}
+}
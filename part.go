@@ -0,0 +1,166 @@
+package m3u8
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// PartialSegment represents the attributes associated with an EXT-X-PART
+// tag. A Partial Segment is a sub-range of a Media Segment that is made
+// available before the Media Segment it belongs to is complete.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.4.9.
+type PartialSegment struct {
+	// URI identifies the resource of the Partial Segment.
+	//
+	// URI is REQUIRED.
+	URI string
+
+	// Duration specifies the duration of the Partial Segment. It MUST NOT
+	// exceed the PartTarget value of the Media Playlist.
+	//
+	// Duration is REQUIRED.
+	Duration time.Duration
+
+	// Independent indicates that the Partial Segment contains an independent
+	// frame.
+	//
+	// Independent is OPTIONAL.
+	Independent bool
+
+	// ByteRange indicates that the Partial Segment is a sub-range of the
+	// resource identified by the URI value.
+	//
+	// If the Start value is negative, the sub-range begins at the next byte
+	// following the sub-range of the previous Partial Segment, which MUST be a
+	// sub-range of the same resource.
+	//
+	// ByteRange is OPTIONAL.
+	ByteRange *ByteRange
+
+	// Gap indicates that the Partial Segment is not available.
+	//
+	// Gap is OPTIONAL.
+	Gap bool
+}
+
+func parsePartialSegment(meta string) (*PartialSegment, error) {
+	attrs, err := parseAttributeList(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var part PartialSegment
+	part.URI, err = attrs.string(attrURI)
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := attrs.float(attrDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	part.Duration = secondsToDuration(duration)
+
+	independent, err := attrs.enum(attrIndependent)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		switch independent {
+		case "YES":
+			part.Independent = true
+		default:
+			return nil, &invalidAttributeValueError{attrIndependent}
+		}
+	}
+
+	byteRange, err := attrs.string(attrByteRange)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		part.ByteRange, err = parseByteRange(byteRange)
+		if err != nil && err != ErrNoRangeStart {
+			return nil, err
+		}
+	}
+
+	gap, err := attrs.enum(attrGap)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		switch gap {
+		case "YES":
+			part.Gap = true
+		default:
+			return nil, &invalidAttributeValueError{attrGap}
+		}
+	}
+
+	return &part, nil
+}
+
+// continues reports whether the Partial Segment may follow prev, which is
+// only relevant when the sub-range of the Partial Segment has an implicit
+// start.
+func (p *PartialSegment) continues(prev *PartialSegment) bool {
+	if p.ByteRange == nil || p.ByteRange.Start >= 0 {
+		return true
+	}
+
+	return prev != nil && prev.ByteRange != nil && prev.URI == p.URI
+}
+
+func (p *PartialSegment) attrs() (attributes, error) {
+	if p.URI == "" {
+		return nil, &missingRequiredAttrError{attrURI}
+	}
+
+	attrs := attributes{
+		attrURI:      p.URI,
+		attrDuration: unsignedFloat(p.Duration.Seconds()),
+	}
+
+	if p.Independent {
+		attrs[attrIndependent] = enumeratedString("YES")
+	}
+
+	if p.ByteRange != nil {
+		attrs[attrByteRange] = p.ByteRange.String()
+	}
+
+	if p.Gap {
+		attrs[attrGap] = enumeratedString("YES")
+	}
+
+	return attrs, nil
+}
+
+func lastPart(parts []*PartialSegment) *PartialSegment {
+	if n := len(parts); n > 0 {
+		return parts[n-1]
+	}
+
+	return nil
+}
+
+func encodeParts(w io.Writer, e *Encoder, parts []*PartialSegment) error {
+	for _, part := range parts {
+		attrs, err := part.attrs()
+		if err != nil {
+			return err
+		}
+
+		encodedAttrs, err := attrs.encode(e.attributeOrder(partTag, part))
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(w, partTag+":"+encodedAttrs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
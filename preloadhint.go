@@ -0,0 +1,146 @@
+package m3u8
+
+type PreloadHintType int
+
+const (
+	PartHint PreloadHintType = iota + 1
+	MapHint
+)
+
+func (t PreloadHintType) String() string {
+	switch t {
+	case PartHint:
+		return "PART"
+	case MapHint:
+		return "MAP"
+	}
+
+	panic("invalid preload hint type")
+}
+
+func ParsePreloadHintType(str string) (PreloadHintType, error) {
+	switch str {
+	case "PART":
+		return PartHint, nil
+	case "MAP":
+		return MapHint, nil
+	}
+
+	return 0, ErrBadPreloadHintType
+}
+
+// PreloadHint represents the attributes associated with an
+// EXT-X-PRELOAD-HINT tag. It allows a client to request a resource that will
+// be required to play the presentation before it is available.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.5.3.
+type PreloadHint struct {
+	// Type specifies the type of the hinted resource.
+	//
+	// Type is REQUIRED.
+	Type PreloadHintType
+
+	// URI identifies the hinted resource.
+	//
+	// URI is REQUIRED.
+	URI string
+
+	// ByteRangeStart is the byte offset of the first byte of the hinted
+	// resource, from the beginning of the resource identified by URI.
+	//
+	// ByteRangeStart is OPTIONAL.
+	ByteRangeStart uint64
+
+	// ByteRangeLength is the length of the hinted resource. A zero-value
+	// indicates that the hinted resource continues until the end of the
+	// resource identified by URI.
+	//
+	// ByteRangeLength is OPTIONAL.
+	ByteRangeLength uint64
+}
+
+func parsePreloadHint(meta string) (*PreloadHint, error) {
+	attrs, err := parseAttributeList(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var h PreloadHint
+	hintType, err := attrs.enum(attrType)
+	if err != nil {
+		return nil, err
+	}
+
+	h.Type, err = ParsePreloadHintType(hintType)
+	if err != nil {
+		return nil, &invalidAttributeValueError{attrType}
+	}
+
+	h.URI, err = attrs.string(attrURI)
+	if err != nil {
+		return nil, err
+	}
+
+	h.ByteRangeStart, err = attrs.integer(attrByteRangeStart)
+	if err != nil && !isMissingAttr(err) {
+		return nil, err
+	}
+
+	h.ByteRangeLength, err = attrs.integer(attrByteRangeLength)
+	if err != nil && !isMissingAttr(err) {
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+func (h *PreloadHint) attrs() (attributes, error) {
+	if h.URI == "" {
+		return nil, &missingRequiredAttrError{attrURI}
+	}
+
+	attrs := attributes{
+		attrType: enumeratedString(h.Type.String()),
+		attrURI:  h.URI,
+	}
+
+	if h.ByteRangeStart > 0 {
+		attrs[attrByteRangeStart] = h.ByteRangeStart
+	}
+
+	if h.ByteRangeLength > 0 {
+		attrs[attrByteRangeLength] = h.ByteRangeLength
+	}
+
+	return attrs, nil
+}
+
+// PreloadHints represents the set of PreloadHint objects in a Media
+// Playlist.
+type PreloadHints []*PreloadHint
+
+// Hint returns the preload hint of the given type.
+//
+// Hint returns nil if no matching preload hint exists.
+func (hs PreloadHints) Hint(t PreloadHintType) *PreloadHint {
+	for _, h := range hs {
+		if h.Type == t {
+			return h
+		}
+	}
+
+	return nil
+}
+
+func (hs PreloadHints) validate() error {
+	seen := map[PreloadHintType]bool{}
+	for _, h := range hs {
+		if seen[h.Type] {
+			return &Error{"more than one preload hint with the same type"}
+		}
+
+		seen[h.Type] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package m3u8
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// The rules that are checked by ValidateReload.
+const (
+	RuleReloadMediaSequence         = "reload-media-sequence"
+	RuleReloadSegment               = "reload-segment"
+	RuleReloadDiscontinuitySequence = "reload-discontinuity-sequence"
+	RuleReloadPlaylistType          = "reload-playlist-type"
+	RuleReloadEndList               = "reload-endlist"
+	RuleReloadStale                 = "reload-stale"
+)
+
+// ValidateReload checks that curr is a valid update of prev, a Media Playlist
+// that was obtained from the same URI elapsed earlier, and returns the
+// problems that were found, ordered by line. The lines refer to curr, and
+// are only known if it was decoded.
+//
+// The staleness of curr is not checked if elapsed is zero.
+//
+// See https://tools.ietf.org/html/rfc8216#section-6.2.1.
+func ValidateReload(prev, curr *MediaPlaylist, elapsed time.Duration) []*Finding {
+	var fs findings
+	fs.checkReload(prev, curr, elapsed)
+
+	sort.SliceStable(fs, func(i, j int) bool {
+		return fs[i].Line < fs[j].Line
+	})
+
+	return fs
+}
+
+func (fs *findings) checkReload(prev, curr *MediaPlaylist, elapsed time.Duration) {
+	if curr.MediaSequence < prev.MediaSequence {
+		fs.add(SeverityError, RuleReloadMediaSequence, 0, fmt.Sprintf("media sequence number decreased from %d to %d", prev.MediaSequence, curr.MediaSequence))
+		return
+	}
+
+	if curr.PlaylistType != prev.PlaylistType {
+		fs.add(SeverityError, RuleReloadPlaylistType, 0, "playlist type changed")
+	} else if curr.PlaylistType == Event && curr.MediaSequence != prev.MediaSequence {
+		fs.add(SeverityError, RuleReloadPlaylistType, 0, "segments were removed from a playlist of type "+Event.String())
+	}
+
+	prevFirst, currFirst := prev.firstSequence(), curr.firstSequence()
+	prevEnd := prevFirst + uint64(len(prev.Segments))
+	currEnd := currFirst + uint64(len(curr.Segments))
+
+	for i, segment := range curr.Segments {
+		seq := currFirst + uint64(i)
+		if seq < prevFirst {
+			continue
+		}
+
+		if seq >= prevEnd {
+			break
+		}
+
+		old := prev.Segments[seq-prevFirst]
+		if segment.URI != old.URI || segment.Duration != old.Duration || segment.Discontinuity != old.Discontinuity {
+			fs.add(SeverityError, RuleReloadSegment, layoutOf(curr.GenericPlaylist).Line(segment), fmt.Sprintf("segment with media sequence number %d changed", seq))
+		}
+	}
+
+	// the discontinuity sequence number is incremented for every removed
+	// discontinuity, which can only be counted if all the removed segments
+	// are known
+	removed := prev.DiscontinuousSequence
+	for seq := prev.MediaSequence; seq < curr.MediaSequence && seq < prevEnd; seq++ {
+		if seq >= prevFirst && prev.Segments[seq-prevFirst].Discontinuity {
+			removed++
+		}
+	}
+
+	known := curr.MediaSequence == prev.MediaSequence || prevFirst == prev.MediaSequence && curr.MediaSequence <= prevEnd
+	if curr.DiscontinuousSequence < removed || known && curr.DiscontinuousSequence != removed {
+		fs.add(SeverityError, RuleReloadDiscontinuitySequence, 0, fmt.Sprintf("discontinuity sequence number, %d, does not match the removed discontinuities", curr.DiscontinuousSequence))
+	}
+
+	if !prev.Live {
+		if curr.Live || curr.MediaSequence != prev.MediaSequence || currEnd != prevEnd {
+			fs.add(SeverityError, RuleReloadEndList, 0, "playlist changed after an "+endlistTag+" tag")
+		}
+
+		return
+	}
+
+	target := time.Duration(curr.TargetDuration) * time.Second
+	if elapsed > 0 && curr.Live && elapsed > target*3/2 && !updated(prev, curr, prevEnd, currEnd) {
+		fs.add(SeverityError, RuleReloadStale, 0, fmt.Sprintf("no new segments, partial segments or preload hints after %s, which exceeds 1.5 times the target duration", elapsed))
+	}
+}
+
+// updated reports whether curr adds Media Segments, Partial Segments or
+// preload hints to prev, given the media sequence numbers that follow their
+// last Media Segments. Playlists with Partial Segments are updated whenever a
+// Partial Segment becomes available, before the Media Segment is complete.
+func updated(prev, curr *MediaPlaylist, prevEnd, currEnd uint64) bool {
+	if currEnd != prevEnd {
+		return currEnd > prevEnd
+	}
+
+	if len(curr.Parts) > len(prev.Parts) {
+		return true
+	}
+
+	for _, h := range curr.PreloadHints {
+		if old := prev.PreloadHints.Hint(h.Type); old == nil || *old != *h {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,98 @@
+package m3u8_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ssttevee/m3u8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateReload(t *testing.T) {
+	decode := func(t *testing.T, data string) *m3u8.MediaPlaylist {
+		d := m3u8.NewDecoder(strings.NewReader(data))
+		d.Lossless = true
+
+		plist, err := d.Decode()
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		return plist.(*m3u8.MediaPlaylist)
+	}
+
+	rules := func(findings []*m3u8.Finding) []string {
+		var rules []string
+		for _, f := range findings {
+			rules = append(rules, f.Rule)
+		}
+
+		return rules
+	}
+
+	prev := decode(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:100\n#EXTINF:4,\n100.ts\n#EXT-X-DISCONTINUITY\n#EXTINF:4,\n101.ts\n#EXTINF:4,\n102.ts\n")
+
+	t.Run("valid update", func(t *testing.T) {
+		curr := decode(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:102\n#EXT-X-DISCONTINUITY-SEQUENCE:1\n#EXTINF:4,\n102.ts\n#EXTINF:4,\n103.ts\n")
+		assert.Empty(t, m3u8.ValidateReload(prev, curr, 4*time.Second))
+	})
+
+	t.Run("media sequence going backwards", func(t *testing.T) {
+		curr := decode(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:99\n#EXTINF:4,\n99.ts\n")
+		assert.Equal(t, []string{m3u8.RuleReloadMediaSequence}, rules(m3u8.ValidateReload(prev, curr, 0)))
+	})
+
+	t.Run("changed segment", func(t *testing.T) {
+		curr := decode(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:101\n#EXT-X-DISCONTINUITY\n#EXTINF:4,\n101.ts\n#EXTINF:3,\nother.ts\n#EXTINF:4,\n103.ts\n")
+
+		findings := m3u8.ValidateReload(prev, curr, 0)
+		if assert.Len(t, findings, 1) {
+			assert.Equal(t, m3u8.RuleReloadSegment, findings[0].Rule)
+			assert.Equal(t, 7, findings[0].Line)
+		}
+	})
+
+	t.Run("discontinuity sequence", func(t *testing.T) {
+		curr := decode(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:102\n#EXTINF:4,\n102.ts\n")
+		assert.Equal(t, []string{m3u8.RuleReloadDiscontinuitySequence}, rules(m3u8.ValidateReload(prev, curr, 0)))
+
+		// the removed segments are unknown
+		curr = decode(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:110\n#EXT-X-DISCONTINUITY-SEQUENCE:3\n#EXTINF:4,\n110.ts\n")
+		assert.Empty(t, m3u8.ValidateReload(prev, curr, 0))
+	})
+
+	t.Run("stale playlist", func(t *testing.T) {
+		assert.Empty(t, m3u8.ValidateReload(prev, prev, 6*time.Second))
+		assert.Equal(t, []string{m3u8.RuleReloadStale}, rules(m3u8.ValidateReload(prev, prev, 7*time.Second)))
+	})
+
+	t.Run("low-latency playlist", func(t *testing.T) {
+		const header = "#EXTM3U\n#EXT-X-VERSION:9\n#EXT-X-TARGETDURATION:4\n#EXT-X-PART-INF:PART-TARGET=1\n#EXT-X-MEDIA-SEQUENCE:100\n#EXTINF:4,\n100.ts\n"
+
+		prev := decode(t, header+"#EXT-X-PART:DURATION=1,URI=\"101.0.ts\"\n#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"101.1.ts\"\n")
+		assert.Equal(t, []string{m3u8.RuleReloadStale}, rules(m3u8.ValidateReload(prev, prev, 7*time.Second)))
+
+		// only a partial segment was added
+		curr := decode(t, header+"#EXT-X-PART:DURATION=1,URI=\"101.0.ts\"\n#EXT-X-PART:DURATION=1,URI=\"101.1.ts\"\n")
+		assert.Empty(t, m3u8.ValidateReload(prev, curr, 7*time.Second))
+
+		// only a preload hint was added
+		curr = decode(t, header+"#EXT-X-PART:DURATION=1,URI=\"101.0.ts\"\n#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"101.1.ts\"\n#EXT-X-PRELOAD-HINT:TYPE=MAP,URI=\"init.mp4\"\n")
+		assert.Empty(t, m3u8.ValidateReload(prev, curr, 7*time.Second))
+	})
+
+	t.Run("ended playlist", func(t *testing.T) {
+		ended := decode(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:100\n#EXTINF:4,\n100.ts\n#EXT-X-ENDLIST\n")
+		assert.Empty(t, m3u8.ValidateReload(ended, ended, time.Minute))
+
+		curr := decode(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:100\n#EXTINF:4,\n100.ts\n#EXTINF:4,\n101.ts\n#EXT-X-ENDLIST\n")
+		assert.Equal(t, []string{m3u8.RuleReloadEndList}, rules(m3u8.ValidateReload(ended, curr, 0)))
+	})
+
+	t.Run("event playlist", func(t *testing.T) {
+		event := decode(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-PLAYLIST-TYPE:EVENT\n#EXTINF:4,\n0.ts\n#EXTINF:4,\n1.ts\n")
+		curr := decode(t, "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-PLAYLIST-TYPE:EVENT\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:4,\n1.ts\n#EXTINF:4,\n2.ts\n")
+		assert.Equal(t, []string{m3u8.RuleReloadPlaylistType}, rules(m3u8.ValidateReload(event, curr, 0)))
+	})
+}
@@ -0,0 +1,72 @@
+package m3u8
+
+// RenditionReport represents the attributes associated with an
+// EXT-X-RENDITION-REPORT tag. It carries information about an associated
+// Rendition that is as up-to-date as the Playlist that contains it.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.5.4.
+type RenditionReport struct {
+	// URI identifies the Media Playlist of the specified Rendition, relative
+	// to the URI of the Media Playlist containing the report.
+	//
+	// URI is REQUIRED.
+	URI string
+
+	// LastMSN specifies the Media Sequence Number of the last Media Segment
+	// currently in the specified Rendition. If the Rendition contains Partial
+	// Segments then this value is the Media Sequence Number of the last
+	// Partial Segment.
+	//
+	// LastMSN is REQUIRED.
+	LastMSN uint64
+
+	// LastPart specifies the Part Index of the last Partial Segment currently
+	// in the specified Rendition.
+	//
+	// LastPart is REQUIRED if the Rendition contains Partial Segments.
+	LastPart *uint64
+}
+
+func parseRenditionReport(meta string) (*RenditionReport, error) {
+	attrs, err := parseAttributeList(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var r RenditionReport
+	r.URI, err = attrs.string(attrURI)
+	if err != nil {
+		return nil, err
+	}
+
+	r.LastMSN, err = attrs.integer(attrLastMSN)
+	if err != nil {
+		return nil, err
+	}
+
+	lastPart, err := attrs.integer(attrLastPart)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		r.LastPart = &lastPart
+	}
+
+	return &r, nil
+}
+
+func (r *RenditionReport) attrs() (attributes, error) {
+	if r.URI == "" {
+		return nil, &missingRequiredAttrError{attrURI}
+	}
+
+	attrs := attributes{
+		attrURI:     r.URI,
+		attrLastMSN: r.LastMSN,
+	}
+
+	if r.LastPart != nil {
+		attrs[attrLastPart] = *r.LastPart
+	}
+
+	return attrs, nil
+}
@@ -14,11 +14,27 @@ const (
 	ClosedCaptions
 )
 
+func (t MediaType) String() string {
+	switch t {
+	case Audio:
+		return "AUDIO"
+	case Video:
+		return "VIDEO"
+	case Subtitles:
+		return "SUBTITLES"
+	case ClosedCaptions:
+		return "CLOSED-CAPTIONS"
+	}
+
+	panic("invalid media type")
+}
+
 type Rendition interface {
 	Type() MediaType
 	applyAttrs(attributes) error
 
 	attrs() (attributes, error)
+	basic() *BasicRendition
 
 	groupID() string
 	name() string
@@ -76,6 +92,13 @@ type BasicRendition struct {
 	//
 	// Characteristics is OPTIONAL.
 	Characteristics []string
+
+	// StableRenditionID allows the URI of the Rendition to change between two
+	// distinct downloads of the Master Playlist. It is also used to identify
+	// the Rendition in the URI replacement rules of a Pathway Clone.
+	//
+	// StableRenditionID is OPTIONAL.
+	StableRenditionID string
 }
 
 func (a *BasicRendition) applyAttrs(attrs attributes) (err error) {
@@ -132,6 +155,13 @@ func (a *BasicRendition) applyAttrs(attrs attributes) (err error) {
 		a.Characteristics = strings.Split(characteristicsStr, ",")
 	}
 
+	a.StableRenditionID, err = attrs.string(attrStableRenditionID)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return err
+	} else if !missing && !isValidStableID(a.StableRenditionID) {
+		return &invalidAttributeValueError{attrStableRenditionID}
+	}
+
 	return nil
 }
 
@@ -184,9 +214,21 @@ func (a BasicRendition) attrs() (attributes, error) {
 		attrs[attrCharacteristics] = strings.Join(a.Characteristics, ",")
 	}
 
+	if a.StableRenditionID != "" {
+		if !isValidStableID(a.StableRenditionID) {
+			return nil, &invalidAttributeValueError{attrStableRenditionID}
+		}
+
+		attrs[attrStableRenditionID] = a.StableRenditionID
+	}
+
 	return attrs, nil
 }
 
+func (a *BasicRendition) basic() *BasicRendition {
+	return a
+}
+
 func (a BasicRendition) groupID() string {
 	return a.GroupID
 }
@@ -251,6 +293,8 @@ func (a *AudioRendition) attrs() (attributes, error) {
 		return nil, err
 	}
 
+	attrs[attrType] = enumeratedString(a.Type().String())
+
 	if a.URI != "" {
 		attrs[attrURI] = a.URI
 	}
@@ -294,6 +338,8 @@ func (a *VideoRendition) attrs() (attributes, error) {
 		return nil, err
 	}
 
+	attrs[attrType] = enumeratedString(a.Type().String())
+
 	if a.URI != "" {
 		attrs[attrURI] = a.URI
 	}
@@ -354,6 +400,8 @@ func (a *SubtitlesRendition) attrs() (attributes, error) {
 		return nil, err
 	}
 
+	attrs[attrType] = enumeratedString(a.Type().String())
+
 	if a.URI != "" {
 		attrs[attrURI] = a.URI
 	}
@@ -383,7 +431,7 @@ func (a *ClosedCaptionsRendition) applyAttrs(attrs attributes) (err error) {
 		return err
 	}
 
-	a.InstreamID, err = attrs.enum(attrInstreamID)
+	a.InstreamID, err = attrs.string(attrInstreamID)
 	if missing := isMissingAttr(err); err != nil && !missing {
 		return err
 	} else if !missing && !isValidInstreamID(a.InstreamID) {
@@ -403,14 +451,18 @@ func (a *ClosedCaptionsRendition) attrs() (attributes, error) {
 		return nil, err
 	}
 
+	attrs[attrType] = enumeratedString(a.Type().String())
+
 	if a.InstreamID == "" {
-		if !isValidInstreamID(a.InstreamID) {
-			return nil, &invalidAttributeValueError{attrInstreamID}
-		}
+		return nil, &missingRequiredAttrError{attrInstreamID}
+	}
 
-		attrs[attrURI] = a.InstreamID
+	if !isValidInstreamID(a.InstreamID) {
+		return nil, &invalidAttributeValueError{attrInstreamID}
 	}
 
+	attrs[attrInstreamID] = a.InstreamID
+
 	return attrs, nil
 }
 
@@ -431,48 +483,10 @@ func isValidInstreamID(str string) bool {
 type renditions []Rendition
 
 func (as renditions) validate() error {
-	if len(as) == 0 {
-		return nil
-	}
-
-	// TODO validate rendition groups: https://tools.ietf.org/html/rfc8216#section-4.3.4.1.1
-
-	type renditionGroup struct {
-		hasDefault bool
-		names      map[string]interface{}
-	}
-
-	typeGroups := map[MediaType]map[string]renditionGroup{}
-	for _, a := range as {
-		t, gid, n, d := a.Type(), a.groupID(), a.name(), a.isDefault()
-		groups, ok := typeGroups[t]
-		if !ok {
-			groups = map[string]renditionGroup{}
-			typeGroups[t] = groups
-		}
-
-		group, ok := groups[gid]
-		if !ok {
-			group = renditionGroup{
-				names: map[string]interface{}{},
-			}
-
-			groups[gid] = group
-		}
-
-		if _, ok := group.names[n]; !ok {
-			if group.hasDefault && d {
-				return &Error{"a rendition group must not have more than one default"}
-			}
+	var fs findings
+	fs.checkRenditions(nil, as)
 
-			group.names[n] = nil
-			group.hasDefault = d
-		} else {
-			return &Error{"all renditions in the same group must have different names"}
-		}
-	}
-
-	return nil
+	return fs.err()
 }
 
 func parseRendition(meta string) (Rendition, error) {
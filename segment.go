@@ -3,6 +3,7 @@ package m3u8
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -52,14 +53,25 @@ type MediaSegment struct {
 	// - encoding sequence
 	Discontinuity bool
 
-	// Key specifies how to decrypt encrypted Media Segments. It applies to
+	// Key specifies how to decrypt an encrypted Media Segment.
+	//
+	// Deprecated: A Media Segment may have keys of multiple key formats, so
+	// Keys should be used instead. Key is only used when Keys is empty. It is
+	// set while decoding to the last of the Keys.
+	Key *Key
+
+	// Keys specify how to decrypt encrypted Media Segments, such as with
+	// different key formats for multiple DRM systems. Each of them applies to
 	// every Media Segment and to every Media Initialization Section declared
-	// by a non-nil Map value that appears between it and the next non-nil Key
-	// value in the Playlist file with the same KeyFormat value (or the end of
-	// the Playlist file).
+	// by a non-nil Map value that appears between it and the next Key in the
+	// Playlist file with the same KeyFormat value (or the end of the Playlist
+	// file).
+	//
+	// Keys with the same KeyFormat value MUST be equal, and a Key with
+	// Method NoEncryption MUST NOT be combined with other keys.
 	//
 	// See https://tools.ietf.org/html/rfc8216#section-4.3.2.4.
-	Key *Key
+	Keys []*Key
 
 	// Map specifies how to obtain the Media Initialization Section required to
 	// parse the applicable Media Segments. It applies to every Media Segment
@@ -72,7 +84,8 @@ type MediaSegment struct {
 	//
 	// NOTE: This is left as a string because there is no easy way to parse the
 	// expansive variations of ISO 8601:2004 formats and section 4.3.2.6 of rfc
-	// 8216 does not require a specific accuracy.
+	// 8216 does not require a specific accuracy. Use ParseProgramDateTime to
+	// parse the common variants.
 	//
 	// See https://tools.ietf.org/html/rfc8216#section-4.3.2.6.
 	ProgramDateTime string
@@ -80,13 +93,66 @@ type MediaSegment struct {
 	// DateRange associates a Date Range (i.e., a range of time defined by a
 	// starting and ending date) with a set of properties.
 	DateRange *DateRange
+
+	// Gap indicates that the Media Segment is missing and that clients should
+	// not attempt to load its URI.
+	//
+	// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.4.7.
+	Gap bool
+
+	// Bitrate specifies the approximate bit rate of the Media Segment, in
+	// kilobits per second. It applies to every Media Segment that appears
+	// after it in the Playlist, up to the next Media Segment with a non-zero
+	// Bitrate value, except Media Segments with a ByteRange value.
+	//
+	// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.4.8.
+	Bitrate uint64
+
+	// Parts are the Partial Segments that make up the Media Segment, in the
+	// order in which they appear in the Playlist file.
+	//
+	// Parts is OPTIONAL.
+	Parts []*PartialSegment
+
+	// CustomTags are the tags with a registered TagHandler of SegmentTag
+	// scope that appear before the URI of the Media Segment and after the URI
+	// of the previous one.
+	CustomTags []*CustomTag
+
+	// RawLines are the comments and unknown tags that appear before the URI
+	// of the Media Segment and after the URI of the previous one. They are
+	// only retained by a Decoder in lossless mode.
+	RawLines []*RawLine
 }
 
-func parseMediaSegment(p *MediaPlaylist, version int, lines []line) (skip int, err error) {
+func parseMediaSegment(p *MediaPlaylist, version int, strict bool, lines []line, collector *errorCollector) (skip int, err error) {
 	var segment MediaSegment
+	var raws, customs, versions int
+
+	// errors are only reported once the lines are known to form a segment,
+	// since they are parsed again as playlist lines otherwise
+	errs := &errorCollector{enabled: collector.enabled}
 
 LinesLoop:
 	for i, line := range lines {
+		if r, ok := line.(raw); ok {
+			segment.RawLines = append(segment.RawLines, &RawLine{
+				Text:   string(r),
+				Offset: i - raws - versions,
+			})
+
+			raws++
+			continue
+		}
+
+		if c, ok := line.(*custom); ok && c.scope == SegmentTag {
+			c.tag.Offset = i - raws - customs - versions
+			segment.CustomTags = append(segment.CustomTags, c.tag)
+
+			customs++
+			continue
+		}
+
 		if uri, ok := line.(uri); ok {
 			if i == 0 {
 				return 0, ErrUnexpectedURI
@@ -94,102 +160,284 @@ LinesLoop:
 
 			segment.URI = string(uri)
 			p.Segments = append(p.Segments, &segment)
+			collector.errs = append(collector.errs, errs.errs...)
 
 			return i, nil
 		}
 
-		s := line.(*split)
+		var s *split
+		switch line := line.(type) {
+		case *split:
+			s = line
+		case *custom:
+			s = line.split
+		}
 
 		var attrs attributes
 		switch s.tag {
+		case versionTag:
+			if i == 0 {
+				break LinesLoop
+			}
+
+			// the version line is only kept to determine the positions of
+			// custom tags and raw lines, and it is encoded with the playlist
+			// header instead
+			versions++
+
 		case infTag:
 			comma := strings.IndexRune(s.meta, ',')
 			if comma == -1 {
-				return 0, ise(s, "missing comma")
+				if err := errs.handle(ise(s, "missing comma")); err != nil {
+					return 0, err
+				}
+
+				continue
 			}
 
 			duration := s.meta[:comma]
 			segment.Title = s.meta[comma+1:]
+			p.Layout.recordLine(&segment, s)
 
 			if version < 3 {
 				if decimal := strings.IndexRune(duration, '.'); decimal != -1 {
-					return 0, &CompatibilityVersionError{s, 3}
+					if err := errs.handle(&CompatibilityVersionError{s, 3}); err != nil {
+						return 0, err
+					}
 				}
 			}
 
 			segment.Duration, err = parseDuration(duration)
 			if err != nil {
-				return 0, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return 0, err
+				}
+
+				continue
 			}
 
 		case byterangeTag:
 			if version < 4 {
-				return 0, &CompatibilityVersionError{s, 4}
+				if err := errs.handle(&CompatibilityVersionError{s, 4}); err != nil {
+					return 0, err
+				}
 			}
 
 			segment.ByteRange, err = parseByteRange(s.meta)
 			if err != nil && (err != ErrNoRangeStart || !p.last().hasDependableRange()) {
-				return 0, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return 0, err
+				}
+
+				continue
 			}
 
 		case discontinuityTag:
 			segment.Discontinuity = true
 
 		case keyTag:
-			segment.Key, err = parseKey(version, s.meta)
+			var key *Key
+			key, err = parseKey(version, strict, s.meta)
+			if err == nil {
+				err = checkKey(segment.Keys, key)
+			}
+
 			if err != nil {
-				return 0, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return 0, err
+				}
+
+				continue
 			}
 
+			p.Layout.record(key, s)
+			segment.Keys = append(segment.Keys, key)
+			segment.Key = key
+
 		case mapTag:
 			attrs, err = parseAttributeList(s.meta)
 			if err != nil {
-				return 0, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return 0, err
+				}
+
+				continue
 			}
 
 			var m Map
 			m.URI, err = attrs.string(attrURI)
 			if err != nil {
-				return 0, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return 0, err
+				}
+
+				continue
 			}
 
 			var strbr string
 			strbr, err = attrs.string(attrByteRange)
 			if err != nil && !isMissingAttr(err) {
-				return 0, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return 0, err
+				}
+
+				continue
 			}
 
-			m.ByteRange, err = parseByteRange(strbr)
-			if err != nil {
-				return 0, isew(s, err)
+			if strbr != "" {
+				m.ByteRange, err = parseByteRange(strbr)
+				if err == ErrNoRangeStart {
+					// a missing offset refers to the end of the sub-range of
+					// the previous media segment, which does not apply to a
+					// media initialization section, so it starts at the
+					// beginning of the resource
+					//
+					// See https://tools.ietf.org/html/rfc8216#section-4.3.2.5
+					m.ByteRange.Start = 0
+				} else if err != nil {
+					if err := errs.handle(isew(s, err)); err != nil {
+						return 0, err
+					}
+
+					continue
+				}
 			}
 
 			m.s = s
+			p.Layout.record(&m, s)
+			segment.Map = &m
 
 		case programDateTimeTag:
 			if err = validateDate(s.meta); err != nil {
-				return 0, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return 0, err
+				}
+
+				continue
 			}
 
 			segment.ProgramDateTime = s.meta
+			p.Layout.recordLine(&segment.ProgramDateTime, s)
 
 		case daterangeTag:
 			segment.DateRange, err = parseDateRange(s.meta)
 			if err != nil {
-				return 0, isew(s, err)
+				if err := errs.handle(isew(s, err)); err != nil {
+					return 0, err
+				}
+
+				continue
+			}
+
+			p.Layout.record(segment.DateRange, s)
+
+		case gapTag:
+			segment.Gap = true
+
+		case bitrateTag:
+			if !rxDecimalInteger.MatchString(s.meta) {
+				if err := errs.handle(isew(s, ErrBadSyntax)); err != nil {
+					return 0, err
+				}
+
+				continue
+			}
+
+			segment.Bitrate, _ = strconv.ParseUint(s.meta, 10, 64)
+
+		case partTag:
+			var part *PartialSegment
+			part, err = parsePartialSegment(s.meta)
+			if err != nil {
+				if err := errs.handle(isew(s, err)); err != nil {
+					return 0, err
+				}
+
+				continue
+			}
+
+			prev := lastPart(segment.Parts)
+			if prev == nil {
+				prev = p.lastPart()
 			}
+
+			if !part.continues(prev) {
+				if err := errs.handle(isew(s, ErrNoRangeStart)); err != nil {
+					return 0, err
+				}
+
+				continue
+			}
+
+			p.Layout.record(part, s)
+			segment.Parts = append(segment.Parts, part)
+
 		default:
-			if i == 0 {
+			if i == 0 || partsOnly(lines[:i]) {
+				// the partial segments of a media segment that is not yet
+				// complete are followed by media playlist tags instead of a
+				// uri
 				break LinesLoop
 			}
 
-			return 0, &Error{msg: `unexpected tag, "` + s.tag + `"`}
+			if err := errs.handle(ise(s, `unexpected tag, "`+s.tag+`"`)); err != nil {
+				return 0, err
+			}
+
+			continue
+		}
+	}
+
+	// the lines after the first one are parsed again as the next segment, so
+	// only the errors of the first line are reported, unless it is a partial
+	// segment that is parsed again as a playlist tag
+	if s, ok := lines[0].(*split); ok && s.tag != partTag {
+		for _, err := range errs.errs {
+			if errorLine(err) == s.num {
+				collector.errs = append(collector.errs, err)
+			}
 		}
 	}
 
 	return 0, ErrNotASegment
 }
 
+// partsOnly reports whether lines only contain partial segments, segment
+// custom tags and raw lines.
+func partsOnly(lines []line) bool {
+	for _, line := range lines {
+		switch line := line.(type) {
+		case raw:
+			continue
+
+		case *custom:
+			if line.scope == SegmentTag {
+				continue
+			}
+
+		case *split:
+			if line.tag == partTag {
+				continue
+			}
+
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// ParseProgramDateTime returns the ProgramDateTime value as a time.Time, or
+// the zero time if it is empty.
+func (s *MediaSegment) ParseProgramDateTime() (time.Time, error) {
+	if s.ProgramDateTime == "" {
+		return time.Time{}, nil
+	}
+
+	return parseDate(s.ProgramDateTime)
+}
+
 func (s *MediaSegment) hasDependableRange() bool {
 	if s == nil {
 		return false
@@ -202,16 +450,18 @@ func (s *MediaSegment) hasDependableRange() bool {
 	return s.ByteRange.closed()
 }
 
-func (s *MediaSegment) encode(w io.Writer) error {
-	if _, err := fmt.Fprintf(w, infTag+":%g,%s\n", s.Duration.Seconds(), s.Title); err != nil {
-		return err
+// keys returns the Keys of the Media Segment, or its deprecated Key if Keys
+// is empty.
+func (s *MediaSegment) keys() []*Key {
+	if len(s.Keys) == 0 && s.Key != nil {
+		return []*Key{s.Key}
 	}
 
-	if s.ByteRange != nil {
-		if _, err := fmt.Fprintln(w, byterangeTag+":"+s.ByteRange.String()); err != nil {
-			return err
-		}
-	}
+	return s.Keys
+}
+
+func (s *MediaSegment) encode(out io.Writer, e *Encoder) error {
+	w := newLineWriter(out, e, s.RawLines, s.CustomTags)
 
 	if s.Discontinuity {
 		if _, err := fmt.Fprintln(w, discontinuityTag); err != nil {
@@ -219,13 +469,18 @@ func (s *MediaSegment) encode(w io.Writer) error {
 		}
 	}
 
-	if s.Key != nil {
-		attrs, err := s.Key.attrs()
+	keys := s.keys()
+	if err := validateKeys(keys); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		attrs, err := key.attrs()
 		if err != nil {
 			return err
 		}
 
-		encodedAttrs, err := attrs.encode()
+		encodedAttrs, err := attrs.encode(e.attributeOrder(keyTag, key))
 		if err != nil {
 			return err
 		}
@@ -241,7 +496,7 @@ func (s *MediaSegment) encode(w io.Writer) error {
 			return err
 		}
 
-		encodedAttrs, err := attrs.encode()
+		encodedAttrs, err := attrs.encode(e.attributeOrder(mapTag, s.Map))
 		if err != nil {
 			return err
 		}
@@ -263,7 +518,7 @@ func (s *MediaSegment) encode(w io.Writer) error {
 			return err
 		}
 
-		encodedAttrs, err := attrs.encode()
+		encodedAttrs, err := attrs.encode(e.attributeOrder(daterangeTag, s.DateRange))
 		if err != nil {
 			return err
 		}
@@ -273,9 +528,35 @@ func (s *MediaSegment) encode(w io.Writer) error {
 		}
 	}
 
+	if s.Gap {
+		if _, err := fmt.Fprintln(w, gapTag); err != nil {
+			return err
+		}
+	}
+
+	if s.Bitrate > 0 {
+		if _, err := fmt.Fprintf(w, bitrateTag+":%d\n", s.Bitrate); err != nil {
+			return err
+		}
+	}
+
+	if err := encodeParts(w, e, s.Parts); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, infTag+":%g,%s\n", s.Duration.Seconds(), s.Title); err != nil {
+		return err
+	}
+
+	if s.ByteRange != nil {
+		if _, err := fmt.Fprintln(w, byterangeTag+":"+s.ByteRange.String()); err != nil {
+			return err
+		}
+	}
+
 	if _, err := fmt.Fprintln(w, s.URI); err != nil {
 		return err
 	}
 
-	return nil
+	return w.flush()
 }
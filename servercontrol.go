@@ -0,0 +1,310 @@
+package m3u8
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ServerControl represents the attributes associated with an
+// EXT-X-SERVER-CONTROL tag.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.3.8.
+type ServerControl struct {
+	// CanSkipUntil indicates that the server can produce Playlist Delta
+	// Updates in response to the _HLS_skip Delivery Directive. It is the
+	// Skip Boundary, which MUST be at least six times the Target Duration.
+	//
+	// CanSkipUntil is OPTIONAL.
+	CanSkipUntil time.Duration
+
+	// CanSkipDateRanges indicates that the server can produce Playlist Delta
+	// Updates that also skip older Date Ranges.
+	//
+	// CanSkipDateRanges MUST NOT be true unless CanSkipUntil is set.
+	//
+	// CanSkipDateRanges is OPTIONAL.
+	CanSkipDateRanges bool
+
+	// HoldBack indicates the server-recommended minimum distance from the end
+	// of the Playlist at which clients should begin to play or to which they
+	// should seek, unless PartHoldBack applies. It MUST be at least three
+	// times the Target Duration.
+	//
+	// HoldBack is OPTIONAL.
+	HoldBack time.Duration
+
+	// PartHoldBack indicates the server-recommended minimum distance from the
+	// end of the Playlist at which clients should begin to play or to which
+	// they should seek when playing in Low-Latency Mode. It MUST be at least
+	// twice the Part Target.
+	//
+	// PartHoldBack is REQUIRED if the Playlist contains an EXT-X-PART-INF tag.
+	PartHoldBack time.Duration
+
+	// CanBlockReload indicates that the server supports Blocking Playlist
+	// Reload.
+	//
+	// CanBlockReload is OPTIONAL.
+	CanBlockReload bool
+}
+
+func parseServerControl(meta string) (*ServerControl, error) {
+	attrs, err := parseAttributeList(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var sc ServerControl
+	canSkipUntil, err := attrs.float(attrCanSkipUntil)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		sc.CanSkipUntil = secondsToDuration(canSkipUntil)
+	}
+
+	canSkipDateRanges, err := attrs.enum(attrCanSkipDateRanges)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		switch canSkipDateRanges {
+		case "YES":
+			sc.CanSkipDateRanges = true
+		default:
+			return nil, &invalidAttributeValueError{attrCanSkipDateRanges}
+		}
+	}
+
+	holdBack, err := attrs.float(attrHoldBack)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		sc.HoldBack = secondsToDuration(holdBack)
+	}
+
+	partHoldBack, err := attrs.float(attrPartHoldBack)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		sc.PartHoldBack = secondsToDuration(partHoldBack)
+	}
+
+	canBlockReload, err := attrs.enum(attrCanBlockReload)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		switch canBlockReload {
+		case "YES":
+			sc.CanBlockReload = true
+		default:
+			return nil, &invalidAttributeValueError{attrCanBlockReload}
+		}
+	}
+
+	return &sc, nil
+}
+
+// validate checks the values of the server control against the target
+// duration and part target of the Media Playlist it belongs to.
+func (sc *ServerControl) validate(targetDuration uint64, partTarget time.Duration) error {
+	target := time.Duration(targetDuration) * time.Second
+
+	if sc.CanSkipUntil > 0 && sc.CanSkipUntil < 6*target {
+		return &Error{`attribute, "` + attrCanSkipUntil + `", must be at least six times the target duration`}
+	}
+
+	if sc.CanSkipDateRanges && sc.CanSkipUntil <= 0 {
+		return &Error{`attribute, "` + attrCanSkipDateRanges + `", requires attribute, "` + attrCanSkipUntil + `",`}
+	}
+
+	if sc.HoldBack > 0 && sc.HoldBack < 3*target {
+		return &Error{`attribute, "` + attrHoldBack + `", must be at least three times the target duration`}
+	}
+
+	if partTarget > 0 && sc.PartHoldBack <= 0 {
+		return &missingRequiredAttrError{attrPartHoldBack}
+	}
+
+	if sc.PartHoldBack > 0 && sc.PartHoldBack < 2*partTarget {
+		return &Error{`attribute, "` + attrPartHoldBack + `", must be at least twice the part target`}
+	}
+
+	return nil
+}
+
+func (sc *ServerControl) attrs() (attributes, error) {
+	attrs := attributes{}
+
+	if sc.CanSkipUntil > 0 {
+		attrs[attrCanSkipUntil] = unsignedFloat(sc.CanSkipUntil.Seconds())
+	}
+
+	if sc.CanSkipDateRanges {
+		attrs[attrCanSkipDateRanges] = enumeratedString("YES")
+	}
+
+	if sc.HoldBack > 0 {
+		attrs[attrHoldBack] = unsignedFloat(sc.HoldBack.Seconds())
+	}
+
+	if sc.PartHoldBack > 0 {
+		attrs[attrPartHoldBack] = unsignedFloat(sc.PartHoldBack.Seconds())
+	}
+
+	if sc.CanBlockReload {
+		attrs[attrCanBlockReload] = enumeratedString("YES")
+	}
+
+	return attrs, nil
+}
+
+const (
+	queryMSN  = "_HLS_msn"
+	queryPart = "_HLS_part"
+	querySkip = "_HLS_skip"
+)
+
+// SkipRequest indicates which parts of a Media Playlist a client asks the
+// server to skip in a Playlist Delta Update.
+type SkipRequest int
+
+const (
+	SkipSegments SkipRequest = iota + 1
+	SkipSegmentsAndDateRanges
+)
+
+func (r SkipRequest) String() string {
+	switch r {
+	case SkipSegments:
+		return "YES"
+	case SkipSegmentsAndDateRanges:
+		return "v2"
+	}
+
+	panic("invalid skip request")
+}
+
+func ParseSkipRequest(str string) (SkipRequest, error) {
+	switch str {
+	case "YES":
+		return SkipSegments, nil
+	case "v2":
+		return SkipSegmentsAndDateRanges, nil
+	}
+
+	return 0, &Error{"invalid value for " + querySkip}
+}
+
+// DeliveryDirectives represents the query parameters that a client adds to
+// a Media Playlist request to ask for a Blocking Playlist Reload or a
+// Playlist Delta Update.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-6.2.5.
+type DeliveryDirectives struct {
+	// MSN is the Media Sequence Number of the Media Segment that the
+	// Playlist response must contain.
+	//
+	// MSN is nil if the request is not a Blocking Playlist Reload.
+	MSN *uint64
+
+	// Part is the index of the Partial Segment of the Media Segment
+	// identified by MSN that the Playlist response must contain.
+	//
+	// Part MUST be nil if MSN is nil.
+	Part *uint64
+
+	// Skip requests a Playlist Delta Update.
+	//
+	// Skip is OPTIONAL.
+	Skip SkipRequest
+}
+
+// ParseDeliveryDirectives parses the Delivery Directives from the query
+// parameters of a Media Playlist request.
+func ParseDeliveryDirectives(query url.Values) (*DeliveryDirectives, error) {
+	var d DeliveryDirectives
+
+	if str := query.Get(queryMSN); str != "" {
+		msn, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return nil, &Error{"invalid value for " + queryMSN}
+		}
+
+		d.MSN = &msn
+	}
+
+	if str := query.Get(queryPart); str != "" {
+		part, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return nil, &Error{"invalid value for " + queryPart}
+		}
+
+		d.Part = &part
+	}
+
+	if str := query.Get(querySkip); str != "" {
+		skip, err := ParseSkipRequest(str)
+		if err != nil {
+			return nil, err
+		}
+
+		d.Skip = skip
+	}
+
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (d *DeliveryDirectives) validate() error {
+	if d.Part != nil && d.MSN == nil {
+		return &Error{queryPart + " requires " + queryMSN}
+	}
+
+	return nil
+}
+
+// Values encodes the Delivery Directives as query parameters.
+func (d *DeliveryDirectives) Values() (url.Values, error) {
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+
+	if d.MSN != nil {
+		query.Set(queryMSN, strconv.FormatUint(*d.MSN, 10))
+	}
+
+	if d.Part != nil {
+		query.Set(queryPart, strconv.FormatUint(*d.Part, 10))
+	}
+
+	if d.Skip != 0 {
+		query.Set(querySkip, d.Skip.String())
+	}
+
+	return query, nil
+}
+
+// Satisfied reports whether p contains the Media Segment or Partial Segment
+// requested by the Delivery Directives, in which case a server may respond
+// to a Blocking Playlist Reload with p.
+func (d *DeliveryDirectives) Satisfied(p *MediaPlaylist) bool {
+	if d.MSN == nil {
+		return true
+	}
+
+	next := p.MediaSequence + uint64(len(p.Segments))
+	if *d.MSN < next {
+		return true
+	}
+
+	if *d.MSN > next || d.Part == nil {
+		return false
+	}
+
+	return uint64(len(p.Parts)) > *d.Part
+}
@@ -3,7 +3,7 @@ package m3u8
 // SessionDataEntry represents the attributes associated with an
 // EXT-X-SESSION-DATA tag.
 //
-// SessionDataEntry MUST contain either a Value or URI value, but nor both.
+// SessionDataEntry MUST contain either a Value or URI value, but not both.
 //
 // See https://tools.ietf.org/html/rfc8216#section-4.3.4.4
 type SessionDataEntry struct {
@@ -42,6 +42,10 @@ func parseSessionDataEntry(meta string) (*SessionDataEntry, error) {
 		return nil, err
 	}
 
+	if attrs.has(attrValue) && attrs.has(attrURI) {
+		return nil, ErrSessionDataValueAndURI
+	}
+
 	value, err := attrs.string(attrValue)
 	if missing := isMissingAttr(err); err != nil && !missing {
 		return nil, err
@@ -64,7 +68,33 @@ func parseSessionDataEntry(meta string) (*SessionDataEntry, error) {
 		}, nil
 	}
 
-	return nil, &Error{"missing value or uri"}
+	return nil, ErrSessionDataValueAndURI
+}
+
+func (sde *SessionDataEntry) attrs() (attributes, error) {
+	if sde.ID == "" {
+		return nil, &missingRequiredAttrError{attrDataID}
+	}
+
+	if (sde.Value == "") == (sde.URI == "") {
+		return nil, ErrSessionDataValueAndURI
+	}
+
+	attrs := attributes{
+		attrDataID: sde.ID,
+	}
+
+	if sde.Language != "" {
+		attrs[attrLanguage] = sde.Language
+	}
+
+	if sde.Value != "" {
+		attrs[attrValue] = sde.Value
+	} else {
+		attrs[attrURI] = sde.URI
+	}
+
+	return attrs, nil
 }
 
 // SessionData represents a set of SessionDataEntry objects and provides
@@ -84,6 +114,26 @@ func (sd SessionData) Entry(id, language string) *SessionDataEntry {
 	return nil
 }
 
+// validate checks that no two entries have the same id and language.
+func (sd SessionData) validate() error {
+	type entryKey struct {
+		id       string
+		language string
+	}
+
+	seen := map[entryKey]bool{}
+	for _, sde := range sd {
+		key := entryKey{sde.ID, sde.Language}
+		if seen[key] {
+			return &Error{`duplicate session data, "` + sde.ID + `",`}
+		}
+
+		seen[key] = true
+	}
+
+	return nil
+}
+
 func (sd *SessionData) getOrCreateEntry(id, language string) *SessionDataEntry {
 	sde := sd.Entry(id, language)
 	if sde == nil {
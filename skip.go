@@ -0,0 +1,221 @@
+package m3u8
+
+import (
+	"strings"
+	"time"
+)
+
+// Skip represents the attributes associated with an EXT-X-SKIP tag. It
+// appears in Playlist Delta Updates in place of the Media Segments that were
+// skipped.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.5.2.
+//
+// Use of Skip REQUIRES a compatibility version number of 9 or greater.
+type Skip struct {
+	// SkippedSegments is the number of Media Segments that have been replaced
+	// by the EXT-X-SKIP tag.
+	//
+	// SkippedSegments is REQUIRED.
+	SkippedSegments uint64
+
+	// DateRangesSkipped indicates that Date Ranges of the skipped Media
+	// Segments have been skipped as well.
+	//
+	// Use of DateRangesSkipped REQUIRES a compatibility version number of 10
+	// or greater.
+	DateRangesSkipped bool
+
+	// RecentlyRemovedDateRanges lists the IDs of Date Ranges that have been
+	// removed from the Playlist recently. It MUST be empty unless
+	// DateRangesSkipped is true.
+	RecentlyRemovedDateRanges []string
+}
+
+func parseSkip(version int, meta string) (*Skip, error) {
+	if version < 9 {
+		return nil, &CompatibilityVersionError{version: 9}
+	}
+
+	attrs, err := parseAttributeList(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var skip Skip
+	skip.SkippedSegments, err = attrs.integer(attrSkippedSegments)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := attrs.string(attrRecentlyRemovedDateRanges)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing {
+		if version < 10 {
+			return nil, &CompatibilityVersionError{version: 10}
+		}
+
+		skip.DateRangesSkipped = true
+		if removed != "" {
+			skip.RecentlyRemovedDateRanges = strings.Split(removed, "\t")
+		}
+	}
+
+	return &skip, nil
+}
+
+func (s *Skip) attrs() (attributes, error) {
+	attrs := attributes{
+		attrSkippedSegments: s.SkippedSegments,
+	}
+
+	if s.DateRangesSkipped {
+		for _, id := range s.RecentlyRemovedDateRanges {
+			if strings.IndexRune(id, '\t') != -1 {
+				return nil, &Error{"date range id may not contain a tab"}
+			}
+		}
+
+		attrs[attrRecentlyRemovedDateRanges] = strings.Join(s.RecentlyRemovedDateRanges, "\t")
+	} else if len(s.RecentlyRemovedDateRanges) > 0 {
+		return nil, &Error{"recently removed date ranges require skipped date ranges"}
+	}
+
+	return attrs, nil
+}
+
+func (s *Skip) removed(id string) bool {
+	for _, removed := range s.RecentlyRemovedDateRanges {
+		if removed == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Delta creates a Playlist Delta Update from p, a complete Media Playlist,
+// in which the Media Segments that are older than the Skip Boundary of p are
+// replaced by an EXT-X-SKIP tag.
+//
+// If skipDateRanges is false, Media Segments that carry a Date Range are
+// never skipped, nor are any Media Segments that follow them.
+//
+// The ServerControl value of p MUST have a non-zero CanSkipUntil value, and
+// a true CanSkipDateRanges value if skipDateRanges is true.
+func (p *MediaPlaylist) Delta(skipDateRanges bool) (*MediaPlaylist, error) {
+	if p.Skip != nil {
+		return nil, &Error{"playlist is already a delta update"}
+	}
+
+	if p.ServerControl == nil || p.ServerControl.CanSkipUntil <= 0 {
+		return nil, &Error{"playlist does not allow delta updates"}
+	}
+
+	if skipDateRanges && !p.ServerControl.CanSkipDateRanges {
+		return nil, &Error{"playlist does not allow skipping date ranges"}
+	}
+
+	var total time.Duration
+	for _, segment := range p.Segments {
+		total += segment.Duration
+	}
+
+	boundary := total - p.ServerControl.CanSkipUntil
+
+	var n int
+	var end time.Duration
+	for _, segment := range p.Segments {
+		if end += segment.Duration; end > boundary {
+			break
+		}
+
+		if segment.DateRange != nil && !skipDateRanges {
+			break
+		}
+
+		n++
+	}
+
+	var base GenericPlaylist
+	if p.GenericPlaylist != nil {
+		base = *p.GenericPlaylist
+	}
+
+	delta := *p
+	delta.GenericPlaylist = &base
+	delta.Segments = append([]*MediaSegment(nil), p.Segments[n:]...)
+	delta.Skip = &Skip{
+		SkippedSegments:   uint64(n),
+		DateRangesSkipped: skipDateRanges,
+	}
+
+	if skipDateRanges {
+		if base.Version < 10 {
+			base.Version = 10
+		}
+	} else if base.Version < 9 {
+		base.Version = 9
+	}
+
+	if n > 0 && len(delta.Segments) > 0 {
+		// the keys and map of the skipped segments still apply to the first
+		// remaining segment
+		first := *delta.Segments[0]
+		if keys := p.EffectiveKeys(n); len(keys) > 0 {
+			first.Keys = keys
+		}
+
+		if first.Map == nil {
+			first.Map = p.EffectiveMap(n - 1)
+		}
+
+		delta.Segments[0] = &first
+	}
+
+	return &delta, nil
+}
+
+// ApplyDelta reconstructs a complete Media Playlist by replacing the
+// EXT-X-SKIP tag of delta with the corresponding Media Segments of p, a
+// previously obtained complete Media Playlist.
+//
+// Date Ranges of the skipped Media Segments are retained unless they are
+// listed as recently removed by delta.
+//
+// If delta is not a Playlist Delta Update, it is returned as is.
+func (p *MediaPlaylist) ApplyDelta(delta *MediaPlaylist) (*MediaPlaylist, error) {
+	if delta.Skip == nil {
+		return delta, nil
+	}
+
+	if p.Skip != nil {
+		return nil, &Error{"cannot apply a delta update to another delta update"}
+	}
+
+	skipped := delta.Skip.SkippedSegments
+	if delta.MediaSequence < p.MediaSequence || delta.MediaSequence+skipped > p.MediaSequence+uint64(len(p.Segments)) {
+		return nil, &Error{"skipped segments are missing from the playlist"}
+	}
+
+	start := delta.MediaSequence - p.MediaSequence
+
+	merged := *delta
+	merged.Skip = nil
+	merged.Segments = make([]*MediaSegment, 0, skipped+uint64(len(delta.Segments)))
+
+	for _, segment := range p.Segments[start : start+skipped] {
+		if segment.DateRange != nil && delta.Skip.removed(segment.DateRange.ID) {
+			s := *segment
+			s.DateRange = nil
+			segment = &s
+		}
+
+		merged.Segments = append(merged.Segments, segment)
+	}
+
+	merged.Segments = append(merged.Segments, delta.Segments...)
+
+	return &merged, nil
+}
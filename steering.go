@@ -0,0 +1,416 @@
+package m3u8
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+)
+
+// ContentSteering represents the attributes associated with an
+// EXT-X-CONTENT-STEERING tag. It allows a server to provide a Content
+// Steering Manifest that directs clients to a preferred Pathway.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-4.4.6.6.
+type ContentSteering struct {
+	// ServerURI identifies the Content Steering Manifest.
+	//
+	// ServerURI is REQUIRED.
+	ServerURI string
+
+	// PathwayID identifies the Pathway that clients should use until the
+	// Content Steering Manifest has been obtained.
+	//
+	// PathwayID is OPTIONAL.
+	PathwayID string
+}
+
+var rxStableID = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// isValidStableID reports whether str only contains characters from the set
+// [a..z], [A..Z], [0..9], '-', '.' and '_', as required for pathway ids and
+// stable ids.
+func isValidStableID(str string) bool {
+	return rxStableID.MatchString(str)
+}
+
+func parseContentSteering(meta string) (*ContentSteering, error) {
+	attrs, err := parseAttributeList(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var cs ContentSteering
+	cs.ServerURI, err = attrs.string(attrServerURI)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.PathwayID, err = attrs.string(attrPathwayID)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return nil, err
+	} else if !missing && !isValidStableID(cs.PathwayID) {
+		return nil, &invalidAttributeValueError{attrPathwayID}
+	}
+
+	return &cs, nil
+}
+
+func (cs *ContentSteering) attrs() (attributes, error) {
+	if cs.ServerURI == "" {
+		return nil, &missingRequiredAttrError{attrServerURI}
+	}
+
+	attrs := attributes{
+		attrServerURI: cs.ServerURI,
+	}
+
+	if cs.PathwayID != "" {
+		if !isValidStableID(cs.PathwayID) {
+			return nil, &invalidAttributeValueError{attrPathwayID}
+		}
+
+		attrs[attrPathwayID] = cs.PathwayID
+	}
+
+	return attrs, nil
+}
+
+// DefaultPathwayID is the Pathway of Variant Streams without a PathwayID
+// value.
+const DefaultPathwayID = "."
+
+// SteeringManifest represents a Content Steering Manifest, which is a JSON
+// document that directs clients to a preferred Pathway.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-7.1.
+type SteeringManifest struct {
+	// Version is the version of the Content Steering Manifest. It MUST be 1.
+	//
+	// Version is REQUIRED.
+	Version int `json:"VERSION"`
+
+	// TTL specifies the number of seconds that the client should wait before
+	// reloading the Content Steering Manifest.
+	//
+	// TTL is REQUIRED.
+	TTL uint64 `json:"TTL"`
+
+	// ReloadURI identifies the resource that the client should use to reload
+	// the Content Steering Manifest.
+	//
+	// ReloadURI is OPTIONAL.
+	ReloadURI string `json:"RELOAD-URI,omitempty"`
+
+	// PathwayPriority lists the Pathway IDs in order of preference.
+	//
+	// PathwayPriority is REQUIRED.
+	PathwayPriority []string `json:"PATHWAY-PRIORITY"`
+
+	// PathwayClones defines new Pathways that are copies of existing ones.
+	//
+	// PathwayClones is OPTIONAL.
+	PathwayClones []*PathwayClone `json:"PATHWAY-CLONES,omitempty"`
+}
+
+// PathwayClone describes a Pathway that is created by copying the Variant
+// Streams of an existing Pathway and replacing their URIs.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-7.2.
+type PathwayClone struct {
+	// BaseID is the Pathway ID of the Pathway to be copied.
+	//
+	// BaseID is REQUIRED.
+	BaseID string `json:"BASE-ID"`
+
+	// ID is the Pathway ID of the new Pathway.
+	//
+	// ID is REQUIRED.
+	ID string `json:"ID"`
+
+	// URIReplacement specifies how to compute the URIs of the new Pathway.
+	//
+	// URIReplacement is REQUIRED.
+	URIReplacement URIReplacement `json:"URI-REPLACEMENT"`
+}
+
+// URIReplacement specifies the URI replacement rules of a PathwayClone.
+//
+// PerVariantURIs and PerRenditionURIs take precedence over Host and
+// QueryParameters.
+type URIReplacement struct {
+	// Host replaces the host of the copied URIs.
+	//
+	// Host is OPTIONAL.
+	Host string `json:"HOST,omitempty"`
+
+	// QueryParameters are added to the query of the copied URIs, replacing
+	// existing parameters with the same name.
+	//
+	// QueryParameters is OPTIONAL.
+	QueryParameters map[string]string `json:"PARAMS,omitempty"`
+
+	// PerVariantURIs maps the StableVariantID of a Variant Stream to the URI
+	// of its copy.
+	//
+	// PerVariantURIs is OPTIONAL.
+	PerVariantURIs map[string]string `json:"PER-VARIANT-URIS,omitempty"`
+
+	// PerRenditionURIs maps the StableRenditionID of a Rendition to the URI
+	// of its copy.
+	//
+	// PerRenditionURIs is OPTIONAL.
+	PerRenditionURIs map[string]string `json:"PER-RENDITION-URIS,omitempty"`
+}
+
+// ParseSteeringManifest parses and validates a Content Steering Manifest.
+func ParseSteeringManifest(data []byte) (*SteeringManifest, error) {
+	var m SteeringManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	if m.Version != 1 {
+		return nil, &Error{"unsupported steering manifest version"}
+	}
+
+	if m.TTL == 0 {
+		return nil, &Error{"missing steering manifest ttl"}
+	}
+
+	if m.ReloadURI != "" {
+		if _, err := url.Parse(m.ReloadURI); err != nil {
+			return nil, &Error{`invalid reload uri, "` + m.ReloadURI + `",`}
+		}
+	}
+
+	if len(m.PathwayPriority) == 0 {
+		return nil, &Error{"missing pathway priority"}
+	}
+
+	ids := map[string]bool{}
+	for _, id := range m.PathwayPriority {
+		if !isValidStableID(id) {
+			return nil, &Error{`invalid pathway id, "` + id + `",`}
+		}
+
+		if ids[id] {
+			return nil, &Error{`duplicate pathway id, "` + id + `",`}
+		}
+
+		ids[id] = true
+	}
+
+	for _, clone := range m.PathwayClones {
+		if clone.BaseID == "" || clone.ID == "" {
+			return nil, &Error{"pathway clone is missing a pathway id"}
+		}
+
+		if !isValidStableID(clone.BaseID) {
+			return nil, &Error{`invalid pathway id, "` + clone.BaseID + `",`}
+		}
+
+		if !isValidStableID(clone.ID) {
+			return nil, &Error{`invalid pathway id, "` + clone.ID + `",`}
+		}
+	}
+
+	return &m, nil
+}
+
+func (r *URIReplacement) apply(uri string, replacement string, base *url.URL) (string, error) {
+	if replacement != "" {
+		return replacement, nil
+	}
+
+	if uri == "" || r.Host == "" && len(r.QueryParameters) == 0 {
+		return uri, nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+
+	if r.Host != "" {
+		if !u.IsAbs() {
+			return "", &Error{`cannot replace the host of relative uri, "` + uri + `",`}
+		}
+
+		u.Host = r.Host
+	}
+
+	if len(r.QueryParameters) > 0 {
+		query := u.Query()
+		for name, value := range r.QueryParameters {
+			query.Set(name, value)
+		}
+
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String(), nil
+}
+
+func pathwayID(id string) string {
+	if id == "" {
+		return DefaultPathwayID
+	}
+
+	return id
+}
+
+// ApplyPathwayClone adds the Variant Streams, I-frame Streams and Renditions
+// of the Pathway described by clone to the Master Playlist.
+//
+// The Renditions that are referred to by the copied Variant Streams are
+// copied into new groups, with the clone ID appended to their GroupID value.
+//
+// base is the URI of the Master Playlist, which is used to resolve relative
+// URIs before their host is replaced. It may be nil if no relative URIs need
+// to be resolved.
+//
+// ApplyPathwayClone does nothing if the Pathway already exists.
+func (p *MasterPlaylist) ApplyPathwayClone(clone *PathwayClone, base *url.URL) error {
+	var variants []*VariantStream
+	for _, vs := range p.VariantStreams {
+		switch pathwayID(vs.PathwayID) {
+		case clone.ID:
+			return nil
+
+		case clone.BaseID:
+			variants = append(variants, vs)
+
+		}
+	}
+
+	if len(variants) == 0 {
+		return &Error{`unknown pathway, "` + clone.BaseID + `",`}
+	}
+
+	replace := &clone.URIReplacement
+
+	existing := map[groupKey]bool{}
+	for _, r := range p.RenditionMap {
+		existing[groupKey{r.Type(), r.groupID()}] = true
+	}
+
+	var renditions []Rendition
+	cloned := map[groupKey]bool{}
+	cloneGroup := func(t MediaType, groupID string) (string, error) {
+		if groupID == "" {
+			return "", nil
+		}
+
+		newID := groupID + "-" + clone.ID
+		if cloned[groupKey{t, newID}] {
+			return newID, nil
+		}
+
+		if existing[groupKey{t, newID}] {
+			return "", &Error{`rendition group, "` + newID + `", already exists`}
+		}
+
+		cloned[groupKey{t, newID}] = true
+
+		for _, r := range p.RenditionMap {
+			if r.Type() != t || r.groupID() != groupID {
+				continue
+			}
+
+			r, err := cloneRendition(r, newID, replace, base)
+			if err != nil {
+				return "", err
+			}
+
+			renditions = append(renditions, r)
+		}
+
+		return newID, nil
+	}
+
+	var err error
+	var clones []*VariantStream
+	for _, vs := range variants {
+		c := *vs
+		c.PathwayID = clone.ID
+
+		c.URI, err = replace.apply(vs.URI, replace.PerVariantURIs[vs.StableVariantID], base)
+		if err != nil {
+			return err
+		}
+
+		for _, ga := range groupAttrs {
+			groupID, err := cloneGroup(ga.t, vs.Group(ga.t))
+			if err != nil {
+				return err
+			}
+
+			c.SetGroup(ga.t, groupID)
+		}
+
+		clones = append(clones, &c)
+	}
+
+	var iframes []*Stream
+	for _, s := range p.IFrameStreams {
+		if pathwayID(s.PathwayID) != clone.BaseID {
+			continue
+		}
+
+		c := *s
+		c.PathwayID = clone.ID
+
+		c.URI, err = replace.apply(s.URI, replace.PerVariantURIs[s.StableVariantID], base)
+		if err != nil {
+			return err
+		}
+
+		c.VideoGroupID, err = cloneGroup(Video, s.VideoGroupID)
+		if err != nil {
+			return err
+		}
+
+		iframes = append(iframes, &c)
+	}
+
+	p.RenditionMap = append(p.RenditionMap, renditions...)
+	p.VariantStreams = append(p.VariantStreams, clones...)
+	p.IFrameStreams = append(p.IFrameStreams, iframes...)
+
+	return nil
+}
+
+func cloneRendition(r Rendition, groupID string, replace *URIReplacement, base *url.URL) (Rendition, error) {
+	var err error
+	switch r := r.(type) {
+	case *AudioRendition:
+		c := *r
+		c.GroupID = groupID
+		c.URI, err = replace.apply(r.URI, replace.PerRenditionURIs[r.StableRenditionID], base)
+		return &c, err
+
+	case *VideoRendition:
+		c := *r
+		c.GroupID = groupID
+		c.URI, err = replace.apply(r.URI, replace.PerRenditionURIs[r.StableRenditionID], base)
+		return &c, err
+
+	case *SubtitlesRendition:
+		c := *r
+		c.GroupID = groupID
+		c.URI, err = replace.apply(r.URI, replace.PerRenditionURIs[r.StableRenditionID], base)
+		return &c, err
+
+	case *ClosedCaptionsRendition:
+		c := *r
+		c.GroupID = groupID
+		return &c, nil
+
+	}
+
+	return nil, &Error{"unsupported rendition type"}
+}
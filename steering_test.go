@@ -0,0 +1,92 @@
+package m3u8_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/ssttevee/m3u8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyPathwayClone(t *testing.T) {
+	const data = "#EXTM3U\n#EXT-X-CONTENT-STEERING:SERVER-URI=\"https://example.com/steering\"\n#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",NAME=\"English\",STABLE-RENDITION-ID=\"en\",URI=\"a/en.m3u8\"\n#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID=\"vid\",NAME=\"Main\",URI=\"a/main.m3u8\"\n#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=86000,VIDEO=\"vid\",URI=\"a/iframe.m3u8\"\n#EXT-X-STREAM-INF:BANDWIDTH=1280000,CODECS=\"avc1.4d401f,mp4a.40.2\",AUDIO=\"aac\",STABLE-VARIANT-ID=\"low\"\na/low.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=2560000,CODECS=\"avc1.4d401f,mp4a.40.2\",AUDIO=\"aac\",STABLE-VARIANT-ID=\"high\"\na/high.m3u8\n"
+
+	const manifest = `{
+		"VERSION": 1,
+		"TTL": 300,
+		"RELOAD-URI": "https://example.com/steering?session=abc",
+		"PATHWAY-PRIORITY": ["CDN-B", "."],
+		"PATHWAY-CLONES": [{
+			"BASE-ID": ".",
+			"ID": "CDN-B",
+			"URI-REPLACEMENT": {
+				"HOST": "b.example.com",
+				"PARAMS": {"cdn": "b"},
+				"PER-VARIANT-URIS": {"high": "https://c.example.com/high.m3u8"}
+			}
+		}]
+	}`
+
+	plist, err := m3u8.DecodePlaylist([]byte(data))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	mplist := plist.(*m3u8.MasterPlaylist)
+
+	sm, err := m3u8.ParseSteeringManifest([]byte(manifest))
+	if !assert.Nil(t, err, "should sucessfully parse the steering manifest") {
+		t.FailNow()
+	}
+
+	assert.Equal(t, uint64(300), sm.TTL)
+	assert.Equal(t, []string{"CDN-B", "."}, sm.PathwayPriority)
+	if !assert.Len(t, sm.PathwayClones, 1) {
+		t.FailNow()
+	}
+
+	base, _ := url.Parse("https://a.example.com/master.m3u8")
+	if !assert.Nil(t, mplist.ApplyPathwayClone(sm.PathwayClones[0], base), "should apply the pathway clone") {
+		t.FailNow()
+	}
+
+	if assert.Len(t, mplist.VariantStreams, 4) {
+		low, high := mplist.VariantStreams[2], mplist.VariantStreams[3]
+		assert.Equal(t, "CDN-B", low.PathwayID)
+		assert.Equal(t, "https://b.example.com/a/low.m3u8?cdn=b", low.URI)
+		assert.Equal(t, "aac-CDN-B", low.GroupID)
+		assert.Equal(t, "https://c.example.com/high.m3u8", high.URI)
+
+		assert.Equal(t, "a/low.m3u8", mplist.VariantStreams[0].URI, "should not modify the base pathway")
+	}
+
+	if assert.Len(t, mplist.IFrameStreams, 2) {
+		assert.Equal(t, "vid-CDN-B", mplist.IFrameStreams[1].VideoGroupID)
+		assert.Equal(t, "vid", mplist.IFrameStreams[0].VideoGroupID, "should not modify the base pathway")
+	}
+
+	if assert.Len(t, mplist.RenditionMap, 4) {
+		a := mplist.RenditionMap[2].(*m3u8.AudioRendition)
+		assert.Equal(t, "aac-CDN-B", a.GroupID)
+		assert.Equal(t, "https://b.example.com/a/en.m3u8?cdn=b", a.URI)
+
+		v := mplist.RenditionMap[3].(*m3u8.VideoRendition)
+		assert.Equal(t, "vid-CDN-B", v.GroupID)
+		assert.Equal(t, "https://b.example.com/a/main.m3u8?cdn=b", v.URI)
+	}
+
+	assert.Nil(t, mplist.ApplyPathwayClone(sm.PathwayClones[0], base), "should ignore existing pathways")
+	assert.Len(t, mplist.VariantStreams, 4)
+
+	_, err = m3u8.ParseSteeringManifest([]byte(`{"VERSION": 2, "TTL": 300, "PATHWAY-PRIORITY": ["."]}`))
+	assert.NotNil(t, err, "should reject unsupported versions")
+
+	_, err = m3u8.ParseSteeringManifest([]byte(`{"VERSION": 1, "PATHWAY-PRIORITY": ["."]}`))
+	assert.NotNil(t, err, "should reject a missing ttl")
+
+	_, err = m3u8.ParseSteeringManifest([]byte(`{"VERSION": 1, "TTL": 300, "RELOAD-URI": "%zz", "PATHWAY-PRIORITY": ["."]}`))
+	assert.NotNil(t, err, "should reject an invalid reload uri")
+
+	_, err = m3u8.ParseSteeringManifest([]byte(`{"VERSION": 1, "TTL": 300, "PATHWAY-PRIORITY": ["CDN-A", "CDN-A"]}`))
+	assert.NotNil(t, err, "should reject duplicate pathway ids")
+}
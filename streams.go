@@ -4,6 +4,129 @@ import (
 	"strings"
 )
 
+type HDCPLevel int
+
+const (
+	HDCPNone HDCPLevel = iota + 1
+	HDCPType0
+	HDCPType1
+)
+
+func (l HDCPLevel) String() string {
+	switch l {
+	case HDCPNone:
+		return "NONE"
+	case HDCPType0:
+		return "TYPE-0"
+	case HDCPType1:
+		return "TYPE-1"
+	}
+
+	panic("invalid hdcp level")
+}
+
+func ParseHDCPLevel(str string) (HDCPLevel, error) {
+	switch str {
+	case "NONE":
+		return HDCPNone, nil
+	case "TYPE-0":
+		return HDCPType0, nil
+	case "TYPE-1":
+		return HDCPType1, nil
+	}
+
+	return 0, ErrBadHDCPLevel
+}
+
+type VideoRange int
+
+const (
+	SDR VideoRange = iota + 1
+	HLG
+	PQ
+)
+
+func (r VideoRange) String() string {
+	switch r {
+	case SDR:
+		return "SDR"
+	case HLG:
+		return "HLG"
+	case PQ:
+		return "PQ"
+	}
+
+	panic("invalid video range")
+}
+
+func ParseVideoRange(str string) (VideoRange, error) {
+	switch str {
+	case "SDR":
+		return SDR, nil
+	case "HLG":
+		return HLG, nil
+	case "PQ":
+		return PQ, nil
+	}
+
+	return 0, ErrBadVideoRange
+}
+
+// ContentProtectionConfiguration is an entry of the ALLOWED-CPC attribute. It
+// lists the Content Protection Configurations that are allowed for the key
+// format.
+type ContentProtectionConfiguration struct {
+	// KeyFormat is the value of the KEYFORMAT attribute of the EXT-X-KEY
+	// tags that the entry applies to.
+	//
+	// KeyFormat is REQUIRED.
+	KeyFormat string
+
+	// CPC is a list of Content Protection Configuration labels, such as
+	// "SW" or "HW", whose meaning is defined by the key system.
+	//
+	// CPC is OPTIONAL.
+	CPC []string
+}
+
+func parseAllowedCPC(str string) ([]*ContentProtectionConfiguration, error) {
+	var cpcs []*ContentProtectionConfiguration
+	for _, entry := range strings.Split(str, ",") {
+		colon := strings.IndexByte(entry, ':')
+		if colon <= 0 {
+			return nil, &invalidAttributeValueError{attrAllowedCPC}
+		}
+
+		cpc := ContentProtectionConfiguration{KeyFormat: entry[:colon]}
+		if labels := entry[colon+1:]; labels != "" {
+			cpc.CPC = strings.Split(labels, "/")
+		}
+
+		cpcs = append(cpcs, &cpc)
+	}
+
+	return cpcs, nil
+}
+
+func encodeAllowedCPC(cpcs []*ContentProtectionConfiguration) (string, error) {
+	entries := make([]string, len(cpcs))
+	for i, cpc := range cpcs {
+		if cpc.KeyFormat == "" || strings.ContainsAny(cpc.KeyFormat, ",:") {
+			return "", &invalidAttributeValueError{attrAllowedCPC}
+		}
+
+		for _, label := range cpc.CPC {
+			if label == "" || strings.ContainsAny(label, ",:/") {
+				return "", &invalidAttributeValueError{attrAllowedCPC}
+			}
+		}
+
+		entries[i] = cpc.KeyFormat + ":" + strings.Join(cpc.CPC, "/")
+	}
+
+	return strings.Join(entries, ","), nil
+}
+
 type Stream struct {
 	// URI identifies the Media Playlist file.
 	//
@@ -46,9 +169,52 @@ type Stream struct {
 	//
 	// See https://tools.ietf.org/html/rfc8216#ref-HDCP.
 	//
-	// HDCP is OPTIONAL.
+	// Deprecated: HDCP is only used when HDCPLevel is not set. It is set
+	// while decoding if HDCPLevel is HDCPType0 or HDCPType1.
 	HDCP bool
 
+	// HDCPLevel indicates the level of High-bandwidth Digital Content
+	// Protection that the output must be protected by for the Variant Stream
+	// to play.
+	//
+	// HDCPLevel is OPTIONAL.
+	HDCPLevel HDCPLevel
+
+	// VideoRange indicates the dynamic range of the video in the Variant
+	// Stream.
+	//
+	// VideoRange is OPTIONAL.
+	VideoRange VideoRange
+
+	// Score indicates the preference of the Variant Stream over the other
+	// Variant Streams in the Master Playlist. A higher value is preferred.
+	//
+	// Score is OPTIONAL.
+	Score *float64
+
+	// SupplementalCodecs is a list of formats that describe media samples that
+	// can be decoded by clients which support additional features, such as
+	// Dolby Vision enhancement layers. Each format may be followed by a "/"
+	// separated list of compatibility brands.
+	//
+	// SupplementalCodecs is OPTIONAL.
+	SupplementalCodecs []string
+
+	// AllowedCPC restricts the Content Protection Configurations that may be
+	// used to play the Variant Stream.
+	//
+	// AllowedCPC is OPTIONAL.
+	AllowedCPC []*ContentProtectionConfiguration
+
+	// ReqVideoLayout lists the video layout specifiers, such as "CH-STEREO"
+	// or "CH-MONO", that a client must support to play the Variant Stream.
+	//
+	// Use of ReqVideoLayout REQUIRES a compatibility version number of 12 or
+	// greater.
+	//
+	// ReqVideoLayout is OPTIONAL.
+	ReqVideoLayout []string
+
 	// GroupID indicates the set of Renditions that SHOULD be used when playing
 	// the presentation.
 	//
@@ -58,9 +224,35 @@ type Stream struct {
 	// If GroupID is set, it indicates that alternative Renditions of the
 	// content are available for playback of that Variant Stream.
 	//
-	// GroupID is OPTIONAL.
+	// Deprecated: A Variant Stream may refer to a group of each type, so
+	// VideoGroupID and the group ids of VariantStream should be used instead.
+	// GroupID is only used by VariantStream, together with its Type, when the
+	// group id of the same type is not set.
 	GroupID string
 
+	// VideoGroupID indicates the set of video Renditions that SHOULD be used
+	// when playing the presentation. It MUST match the GroupID value of a
+	// video Rendition in the Master Playlist.
+	//
+	// VideoGroupID is OPTIONAL.
+	VideoGroupID string
+
+	// PathwayID indicates the Content Steering Pathway that the Variant Stream
+	// belongs to. A zero-value indicates the default Pathway, ".".
+	//
+	// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-7.
+	//
+	// PathwayID is OPTIONAL.
+	PathwayID string
+
+	// StableVariantID allows the URI of the Variant Stream to change between
+	// two distinct downloads of the Master Playlist. It is also used to
+	// identify the Variant Stream in the URI replacement rules of a Pathway
+	// Clone.
+	//
+	// StableVariantID is OPTIONAL.
+	StableVariantID string
+
 	// ProgramID uniquely identifies a particular presentation within the scope
 	// of the Playlist file.
 	//
@@ -72,7 +264,7 @@ type Stream struct {
 	ProgramID uint64
 }
 
-func (s *Stream) applyAttributes(attrs attributes) (err error) {
+func (s *Stream) applyAttributes(version int, attrs attributes) (err error) {
 	s.Bandwidth, err = attrs.integer(attrBandwidth)
 	if err != nil {
 		return err
@@ -95,17 +287,61 @@ func (s *Stream) applyAttributes(attrs attributes) (err error) {
 		return err
 	}
 
-	hdcpLevel, err := attrs.string(attrHDCPLevel)
+	hdcpLevel, err := attrs.enum(attrHDCPLevel)
 	if missing := isMissingAttr(err); err != nil && !missing {
 		return err
 	} else if !missing {
-		switch hdcpLevel {
-		case "TYPE-0":
-			s.HDCP = true
-		case "NONE":
-		default:
+		s.HDCPLevel, err = ParseHDCPLevel(hdcpLevel)
+		if err != nil {
 			return &invalidAttributeValueError{attrHDCPLevel}
 		}
+
+		s.HDCP = s.HDCPLevel != HDCPNone
+	}
+
+	videoRange, err := attrs.enum(attrVideoRange)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return err
+	} else if !missing {
+		s.VideoRange, err = ParseVideoRange(videoRange)
+		if err != nil {
+			return &invalidAttributeValueError{attrVideoRange}
+		}
+	}
+
+	score, err := attrs.float(attrScore)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return err
+	} else if !missing {
+		s.Score = &score
+	}
+
+	supplementalCodecs, err := attrs.string(attrSupplementalCodecs)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return err
+	} else if !missing {
+		s.SupplementalCodecs = strings.Split(supplementalCodecs, ",")
+	}
+
+	allowedCPC, err := attrs.string(attrAllowedCPC)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return err
+	} else if !missing {
+		s.AllowedCPC, err = parseAllowedCPC(allowedCPC)
+		if err != nil {
+			return err
+		}
+	}
+
+	reqVideoLayout, err := attrs.string(attrReqVideoLayout)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return err
+	} else if !missing {
+		if version < 12 {
+			return &CompatibilityVersionError{version: 12}
+		}
+
+		s.ReqVideoLayout = strings.Split(reqVideoLayout, ",")
 	}
 
 	s.ProgramID, err = attrs.integer(attrProgramID)
@@ -113,6 +349,25 @@ func (s *Stream) applyAttributes(attrs attributes) (err error) {
 		return err
 	}
 
+	s.PathwayID, err = attrs.string(attrPathwayID)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return err
+	} else if !missing && !isValidStableID(s.PathwayID) {
+		return &invalidAttributeValueError{attrPathwayID}
+	}
+
+	s.StableVariantID, err = attrs.string(attrStableVariantID)
+	if missing := isMissingAttr(err); err != nil && !missing {
+		return err
+	} else if !missing && !isValidStableID(s.StableVariantID) {
+		return &invalidAttributeValueError{attrStableVariantID}
+	}
+
+	s.VideoGroupID, err = attrs.string(attrVideo)
+	if err != nil && !isMissingAttr(err) {
+		return err
+	}
+
 	return nil
 }
 
@@ -136,17 +391,67 @@ func (s *Stream) attrs() (attributes, error) {
 		attrs[attrCodecs] = strings.Join(s.Codecs, ",")
 	}
 
+	if s.HDCPLevel != 0 {
+		attrs[attrHDCPLevel] = enumeratedString(s.HDCPLevel.String())
+	} else if s.HDCP {
+		attrs[attrHDCPLevel] = enumeratedString(HDCPType0.String())
+	}
+
+	if s.VideoRange != 0 {
+		attrs[attrVideoRange] = enumeratedString(s.VideoRange.String())
+	}
+
+	if s.Score != nil {
+		attrs[attrScore] = unsignedFloat(*s.Score)
+	}
+
+	if len(s.SupplementalCodecs) > 0 {
+		attrs[attrSupplementalCodecs] = strings.Join(s.SupplementalCodecs, ",")
+	}
+
+	if len(s.AllowedCPC) > 0 {
+		allowedCPC, err := encodeAllowedCPC(s.AllowedCPC)
+		if err != nil {
+			return nil, err
+		}
+
+		attrs[attrAllowedCPC] = allowedCPC
+	}
+
+	if len(s.ReqVideoLayout) > 0 {
+		attrs[attrReqVideoLayout] = strings.Join(s.ReqVideoLayout, ",")
+	}
+
+	if s.PathwayID != "" {
+		if !isValidStableID(s.PathwayID) {
+			return nil, &invalidAttributeValueError{attrPathwayID}
+		}
+
+		attrs[attrPathwayID] = s.PathwayID
+	}
+
+	if s.StableVariantID != "" {
+		if !isValidStableID(s.StableVariantID) {
+			return nil, &invalidAttributeValueError{attrStableVariantID}
+		}
+
+		attrs[attrStableVariantID] = s.StableVariantID
+	}
+
+	if s.VideoGroupID != "" {
+		attrs[attrVideo] = s.VideoGroupID
+	}
+
 	return attrs, nil
 }
 
 // VariantStream represents a Variant Stream, which is a set of Renditions that
 // can be combined to play the presentation.
 //
-// Note: The AUDIO, VIDEO, SUBTITLES and CLOSED-CAPTIONS attributes of the
-// EXT-X-STREAM-INF have been replaced with the Type and GroupID fields. Since
-// only one of the aforementioned attributes are ever used at once and all are
-// used to indicate a group id, it makes more sense to have this kind of data
-// structure.
+// The AUDIO, VIDEO, SUBTITLES and CLOSED-CAPTIONS attributes of the
+// EXT-X-STREAM-INF tag are represented by the AudioGroupID, VideoGroupID,
+// SubtitlesGroupID and ClosedCaptionsGroupID fields. Group and SetGroup may be
+// used to access them by MediaType.
 //
 // See https://tools.ietf.org/html/rfc8216#section-4.3.4.2.
 type VariantStream struct {
@@ -158,12 +463,95 @@ type VariantStream struct {
 	// FrameRate is OPTIONAL.
 	FrameRate float64
 
-	// Type indicates the type of the Variant Stream.
+	// AudioGroupID indicates the set of audio Renditions that SHOULD be used
+	// when playing the presentation. It MUST match the GroupID value of an
+	// audio Rendition in the Master Playlist.
+	//
+	// AudioGroupID is OPTIONAL.
+	AudioGroupID string
+
+	// SubtitlesGroupID indicates the set of subtitles Renditions that can be
+	// used when playing the presentation. It MUST match the GroupID value of
+	// a subtitles Rendition in the Master Playlist.
 	//
-	// Type must be set if GroupID is set.
+	// SubtitlesGroupID is OPTIONAL.
+	SubtitlesGroupID string
+
+	// ClosedCaptionsGroupID indicates the set of closed-captions Renditions
+	// that can be used when playing the presentation. It MUST match the
+	// GroupID value of a closed-captions Rendition in the Master Playlist.
+	//
+	// ClosedCaptionsGroupID is OPTIONAL.
+	ClosedCaptionsGroupID string
+
+	// NoClosedCaptions indicates that there are no closed captions in any
+	// Variant Stream in the Master Playlist. It is encoded as
+	// CLOSED-CAPTIONS=NONE and MUST NOT be used with ClosedCaptionsGroupID.
+	//
+	// NoClosedCaptions is OPTIONAL.
+	NoClosedCaptions bool
+
+	// Type indicates the type of the group referred to by GroupID.
+	//
+	// Deprecated: Type is set to the type of the first group id found while
+	// decoding, in the order audio, video, subtitles and closed-captions. Use
+	// Group and SetGroup instead.
 	Type MediaType
 }
 
+// Group returns the id of the group of Renditions of type t that the Variant
+// Stream refers to, or an empty string if it does not refer to one.
+func (vs *VariantStream) Group(t MediaType) string {
+	var groupID string
+	switch t {
+	case Audio:
+		groupID = vs.AudioGroupID
+	case Video:
+		groupID = vs.VideoGroupID
+	case Subtitles:
+		groupID = vs.SubtitlesGroupID
+	case ClosedCaptions:
+		groupID = vs.ClosedCaptionsGroupID
+	}
+
+	if groupID == "" && vs.Type == t {
+		return vs.GroupID
+	}
+
+	return groupID
+}
+
+// SetGroup sets the id of the group of Renditions of type t that the Variant
+// Stream refers to. An empty groupID removes the reference.
+func (vs *VariantStream) SetGroup(t MediaType, groupID string) {
+	switch t {
+	case Audio:
+		vs.AudioGroupID = groupID
+	case Video:
+		vs.VideoGroupID = groupID
+	case Subtitles:
+		vs.SubtitlesGroupID = groupID
+	case ClosedCaptions:
+		vs.ClosedCaptionsGroupID = groupID
+	default:
+		return
+	}
+
+	if vs.Type == t {
+		vs.GroupID = groupID
+	}
+}
+
+var groupAttrs = []struct {
+	t    MediaType
+	name string
+}{
+	{Audio, attrAudio},
+	{Video, attrVideo},
+	{Subtitles, attrSubtitles},
+	{ClosedCaptions, attrClosedCaptions},
+}
+
 func (s *VariantStream) attrs() (attributes, error) {
 	attrs, err := s.Stream.attrs()
 	if err != nil {
@@ -174,30 +562,31 @@ func (s *VariantStream) attrs() (attributes, error) {
 		attrs[attrFrameRate] = s.FrameRate
 	}
 
-	if s.GroupID != "" {
-		switch s.Type {
-		case Audio:
-			attrs[attrAudio] = s.GroupID
-		case Video:
-			attrs[attrVideo] = s.GroupID
-		case Subtitles:
-			attrs[attrSubtitles] = s.GroupID
-		case ClosedCaptions:
-			attrs[attrClosedCaptions] = s.GroupID
+	for _, ga := range groupAttrs {
+		if groupID := s.Group(ga.t); groupID != "" {
+			attrs[ga.name] = groupID
 		}
 	}
 
+	if s.NoClosedCaptions {
+		if s.Group(ClosedCaptions) != "" {
+			return nil, &invalidAttributeValueError{attrClosedCaptions}
+		}
+
+		attrs[attrClosedCaptions] = enumeratedString("NONE")
+	}
+
 	return attrs, nil
 }
 
-func parseVariantStream(meta string) (*VariantStream, error) {
+func parseVariantStream(version int, meta string) (*VariantStream, error) {
 	attrs, err := parseAttributeList(meta)
 	if err != nil {
 		return nil, err
 	}
 
 	var vs VariantStream
-	if err := vs.applyAttributes(attrs); err != nil {
+	if err := vs.applyAttributes(version, attrs); err != nil {
 		return nil, err
 	}
 
@@ -206,28 +595,41 @@ func parseVariantStream(meta string) (*VariantStream, error) {
 		return nil, err
 	}
 
-	if vs.GroupID, err = attrs.string(attrAudio); err == nil {
-		vs.Type = Audio
-	} else if !isMissingAttr(err) {
-		return nil, err
-	} else if vs.GroupID, err = attrs.string(attrVideo); err == nil {
-		vs.Type = Video
-	} else if !isMissingAttr(err) {
-		return nil, err
-	} else if vs.GroupID, err = attrs.string(attrSubtitles); err == nil {
-		vs.Type = Subtitles
-	} else if !isMissingAttr(err) {
+	vs.AudioGroupID, err = attrs.string(attrAudio)
+	if err != nil && !isMissingAttr(err) {
 		return nil, err
-	} else if vs.GroupID, err = attrs.string(attrClosedCaptions); err == nil {
-		vs.Type = ClosedCaptions
-	} else if !isMissingAttr(err) {
+	}
+
+	vs.SubtitlesGroupID, err = attrs.string(attrSubtitles)
+	if err != nil && !isMissingAttr(err) {
 		return nil, err
 	}
 
+	if attrs.has(attrClosedCaptions) {
+		if none, err := attrs.enum(attrClosedCaptions); err == nil {
+			if none != "NONE" {
+				return nil, &invalidAttributeValueError{attrClosedCaptions}
+			}
+
+			vs.NoClosedCaptions = true
+		} else if vs.ClosedCaptionsGroupID, err = attrs.string(attrClosedCaptions); err != nil {
+			return nil, err
+		}
+	}
+
+	// fill in the deprecated fields for existing callers
+	for _, ga := range groupAttrs {
+		if groupID := vs.Group(ga.t); groupID != "" {
+			vs.Type = ga.t
+			vs.GroupID = groupID
+			break
+		}
+	}
+
 	return &vs, nil
 }
 
-func parseIFrameStream(meta string) (*Stream, error) {
+func parseIFrameStream(version int, meta string) (*Stream, error) {
 	attrs, err := parseAttributeList(meta)
 	if err != nil {
 		return nil, err
@@ -238,7 +640,7 @@ func parseIFrameStream(meta string) (*Stream, error) {
 		return nil, err
 	}
 
-	if err := s.applyAttributes(attrs); err != nil {
+	if err := s.applyAttributes(version, attrs); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,126 @@
+package m3u8
+
+import (
+	"strings"
+)
+
+// TagScope indicates what a custom tag applies to.
+type TagScope int
+
+const (
+	// SegmentTag indicates that a custom tag applies to the next Media
+	// Segment, like the Media Segment tags of the specification. Segment tags
+	// that are not followed by a Media Segment apply to the Media Playlist.
+	SegmentTag TagScope = iota + 1
+
+	// PlaylistTag indicates that a custom tag applies to the entire Playlist.
+	PlaylistTag
+)
+
+// TagHandler parses and encodes a tag that is not defined by the
+// specification, such as #EXT-X-CUE-OUT or #EXT-OATCLS-SCTE35.
+type TagHandler struct {
+	// Scope indicates whether the tag is attached to a Media Segment or to
+	// the Playlist. Segment tags are attached to the Playlist in a Master
+	// Playlist.
+	Scope TagScope
+
+	// Parse returns the value of the tag given the text that follows its
+	// colon, or an empty string if the tag has no colon.
+	//
+	// Parse is OPTIONAL; if it is nil, the text itself is used as the value.
+	Parse func(value string) (interface{}, error)
+
+	// Encode returns the text that follows the colon of the tag. If the text
+	// is empty, the tag is encoded without a colon.
+	//
+	// Encode is OPTIONAL; if it is nil, the value must be a string.
+	Encode func(value interface{}) (string, error)
+}
+
+// CustomTag is a tag that was parsed by a TagHandler.
+type CustomTag struct {
+	// Name is the name of the tag, including the leading "#EXT".
+	Name string
+
+	// Value is the value of the tag, as returned by the Parse function of the
+	// TagHandler.
+	Value interface{}
+
+	// Offset is the number of lines of the element that the CustomTag belongs
+	// to which precede it. Other custom tags, raw lines and blank lines are
+	// not counted. For playlists, the header tag is not counted either, so an
+	// Offset of zero places the tag right after it.
+	//
+	// The CustomTags of an element must be ordered by Offset.
+	Offset int
+}
+
+// custom is a line that contains a registered custom tag.
+type custom struct {
+	*split
+
+	scope TagScope
+	tag   *CustomTag
+}
+
+type tagHandlers map[string]*TagHandler
+
+func (th *tagHandlers) register(name string, h *TagHandler) {
+	if !strings.HasPrefix(name, tagPrefix) || strings.ContainsAny(name, ":\r\n") {
+		panic(`m3u8: invalid tag name, "` + name + `"`)
+	}
+
+	if *th == nil {
+		*th = tagHandlers{}
+	}
+
+	(*th)[name] = h
+}
+
+func (th tagHandlers) parse(s *split) (*custom, error) {
+	h := th[s.tag]
+
+	value := interface{}(s.meta)
+	if h.Parse != nil {
+		var err error
+		value, err = h.Parse(s.meta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &custom{
+		split: s,
+		scope: h.Scope,
+		tag: &CustomTag{
+			Name:  s.tag,
+			Value: value,
+		},
+	}, nil
+}
+
+func (th tagHandlers) encode(tag *CustomTag) (string, error) {
+	var value string
+	if h := th[tag.Name]; h != nil && h.Encode != nil {
+		var err error
+		value, err = h.Encode(tag.Value)
+		if err != nil {
+			return "", err
+		}
+	} else if str, ok := tag.Value.(string); ok {
+		value = str
+	} else {
+		return "", &Error{`no encoder for custom tag, "` + tag.Name + `",`}
+	}
+
+	if strings.ContainsAny(value, "\r\n") {
+		return "", &Error{`illegal value for custom tag, "` + tag.Name + `",`}
+	}
+
+	if value == "" {
+		return tag.Name, nil
+	}
+
+	return tag.Name + ":" + value, nil
+}
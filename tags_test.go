@@ -0,0 +1,81 @@
+package m3u8_test
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/ssttevee/m3u8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomTags(t *testing.T) {
+	const data = "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:10\n#EXT-X-PROVIDER:example\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:10,\nfirst.ts\n#EXT-X-CUE-OUT:20\n#EXTINF:10,\nad1.ts\n#EXTINF:10,\nad2.ts\n#EXT-X-CUE-IN\n#EXTINF:10,\nsecond.ts\n#EXT-X-CUE-OUT:10\n#EXT-X-ENDLIST\n"
+
+	cueOut := &m3u8.TagHandler{
+		Scope: m3u8.SegmentTag,
+		Parse: func(value string) (interface{}, error) {
+			return strconv.ParseFloat(value, 64)
+		},
+		Encode: func(value interface{}) (string, error) {
+			return strconv.FormatFloat(value.(float64), 'f', -1, 64), nil
+		},
+	}
+
+	cueIn := &m3u8.TagHandler{Scope: m3u8.SegmentTag}
+	provider := &m3u8.TagHandler{Scope: m3u8.PlaylistTag}
+
+	d := m3u8.NewDecoder(bytes.NewReader([]byte(data)))
+	d.RegisterTag("#EXT-X-CUE-OUT", cueOut)
+	d.RegisterTag("#EXT-X-CUE-IN", cueIn)
+	d.RegisterTag("#EXT-X-PROVIDER", provider)
+
+	plist, err := d.Decode()
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	mplist := plist.(*m3u8.MediaPlaylist)
+
+	if assert.Len(t, mplist.CustomTags, 2) {
+		assert.Equal(t, &m3u8.CustomTag{Name: "#EXT-X-PROVIDER", Value: "example", Offset: 2}, mplist.CustomTags[0])
+		assert.Equal(t, "#EXT-X-CUE-OUT", mplist.CustomTags[1].Name, "should attach segment tags that are not followed by a segment to the playlist")
+	}
+
+	if assert.Len(t, mplist.Segments, 4) {
+		assert.Empty(t, mplist.Segments[0].CustomTags)
+		if assert.Len(t, mplist.Segments[1].CustomTags, 1) {
+			assert.Equal(t, &m3u8.CustomTag{Name: "#EXT-X-CUE-OUT", Value: float64(20)}, mplist.Segments[1].CustomTags[0])
+		}
+
+		if assert.Len(t, mplist.Segments[3].CustomTags, 1) {
+			assert.Equal(t, &m3u8.CustomTag{Name: "#EXT-X-CUE-IN", Value: ""}, mplist.Segments[3].CustomTags[0])
+		}
+	}
+
+	var buf bytes.Buffer
+	e := m3u8.NewEncoder(&buf)
+	e.RegisterTag("#EXT-X-CUE-OUT", cueOut)
+
+	if assert.Nil(t, e.Encode(mplist), "should successfully encode") {
+		assert.Equal(t, data, buf.String())
+	}
+
+	err = m3u8.NewEncoder(&buf).Encode(mplist)
+	assert.NotNil(t, err, "should not encode custom tags without an encoder")
+
+	_, err = m3u8.DecodePlaylist([]byte(data))
+	assert.IsType(t, &m3u8.UnexpectedTagError{}, err, "should reject unregistered tags")
+
+	d = m3u8.NewDecoder(bytes.NewReader([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\n#EXT-X-VERSION:3\n#EXT-X-CUE-IN\nfirst.ts\n")))
+	d.RegisterTag("#EXT-X-CUE-IN", cueIn)
+
+	plist, err = d.Decode()
+	if assert.Nil(t, err, "should accept the version tag within a media segment") {
+		mplist := plist.(*m3u8.MediaPlaylist)
+		assert.Equal(t, 3, mplist.Version)
+		if assert.Len(t, mplist.Segments, 1) && assert.Len(t, mplist.Segments[0].CustomTags, 1) {
+			assert.Equal(t, &m3u8.CustomTag{Name: "#EXT-X-CUE-IN", Value: "", Offset: 1}, mplist.Segments[0].CustomTags[0])
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package m3u8
+
+import (
+	"sort"
+	"time"
+)
+
+// DateTimeline maps the Media Segments of a Media Playlist to wall-clock time.
+type DateTimeline struct {
+	starts    []time.Time
+	durations []time.Duration
+}
+
+// DateTimeline returns the wall-clock time of every Media Segment in the
+// playlist.
+//
+// The date and time of a Media Segment without a ProgramDateTime value is
+// interpolated from the nearest one that does, using the durations of the
+// Media Segments in between. Interpolation does not cross discontinuities, so
+// the date and time of every Media Segment between two discontinuities is
+// unknown if none of them has a ProgramDateTime value.
+//
+// See https://tools.ietf.org/html/rfc8216#section-4.3.2.6.
+func (p *MediaPlaylist) DateTimeline() (*DateTimeline, error) {
+	tl := &DateTimeline{
+		starts:    make([]time.Time, len(p.Segments)),
+		durations: make([]time.Duration, len(p.Segments)),
+	}
+
+	for i, segment := range p.Segments {
+		tl.durations[i] = segment.Duration
+
+		t, err := segment.ParseProgramDateTime()
+		if err != nil {
+			return nil, err
+		}
+
+		if t.IsZero() && i > 0 && !segment.Discontinuity && !tl.starts[i-1].IsZero() {
+			t = tl.starts[i-1].Add(tl.durations[i-1])
+		}
+
+		tl.starts[i] = t
+	}
+
+	// the segments that precede the first date and time after a discontinuity
+	for i := len(p.Segments) - 2; i >= 0; i-- {
+		if tl.starts[i].IsZero() && !p.Segments[i+1].Discontinuity && !tl.starts[i+1].IsZero() {
+			tl.starts[i] = tl.starts[i+1].Add(-tl.durations[i])
+		}
+	}
+
+	return tl, nil
+}
+
+// Start returns the date and time of the first sample of the Media Segment at
+// index i of Segments, or the zero time if it is unknown.
+func (tl *DateTimeline) Start(i int) time.Time {
+	return tl.starts[i]
+}
+
+// Locate returns the index in Segments of the first Media Segment that
+// contains the date and time t, along with the offset of t from the start of
+// the Media Segment.
+//
+// If no Media Segment contains t, ok is false.
+func (tl *DateTimeline) Locate(t time.Time) (index int, offset time.Duration, ok bool) {
+	for i, start := range tl.starts {
+		if start.IsZero() || t.Before(start) {
+			continue
+		}
+
+		if offset := t.Sub(start); offset < tl.durations[i] {
+			return i, offset, true
+		}
+	}
+
+	return -1, 0, false
+}
+
+// SegmentIndex maps playback offsets to the Media Segments of a Media
+// Playlist. The offsets are relative to the start of the first Media Segment
+// in the playlist.
+//
+// The index is not updated when the playlist is modified.
+type SegmentIndex struct {
+	// offsets are the start offsets of the segments, followed by the total
+	// duration
+	offsets []time.Duration
+}
+
+// SegmentIndex returns an index of the Media Segments in the playlist that
+// can be used to look up Media Segments by offset in O(log n) time.
+func (p *MediaPlaylist) SegmentIndex() *SegmentIndex {
+	idx := &SegmentIndex{
+		offsets: make([]time.Duration, len(p.Segments)+1),
+	}
+
+	for i, segment := range p.Segments {
+		idx.offsets[i+1] = idx.offsets[i] + segment.Duration
+	}
+
+	return idx
+}
+
+// Duration returns the sum of the durations of the Media Segments.
+func (idx *SegmentIndex) Duration() time.Duration {
+	return idx.offsets[len(idx.offsets)-1]
+}
+
+// Offset returns the offset of the start of the Media Segment at index i of
+// Segments.
+func (idx *SegmentIndex) Offset(i int) time.Duration {
+	return idx.offsets[i]
+}
+
+// Find returns the index in Segments of the Media Segment that contains the
+// playback offset, along with the offset from the start of the Media Segment.
+//
+// If no Media Segment contains the offset, ok is false.
+func (idx *SegmentIndex) Find(offset time.Duration) (index int, segmentOffset time.Duration, ok bool) {
+	if offset < 0 || offset >= idx.Duration() {
+		return -1, 0, false
+	}
+
+	// the first segment that ends after the offset, which skips the segments
+	// with a zero duration
+	i := sort.Search(len(idx.offsets)-1, func(i int) bool {
+		return idx.offsets[i+1] > offset
+	})
+
+	return i, offset - idx.offsets[i], true
+}
+
+// ResolveStart returns the index in Segments of the Media Segment at which
+// playback should start according to start, along with the offset from the
+// start of the Media Segment at which media samples should be rendered.
+//
+// The offset is zero unless start is Precise. A time offset beyond either end
+// of the playlist indicates that end.
+//
+// If there are no Media Segments, ok is false.
+//
+// See https://tools.ietf.org/html/rfc8216#section-4.3.5.2.
+func (idx *SegmentIndex) ResolveStart(start *Start) (index int, segmentOffset time.Duration, ok bool) {
+	n := len(idx.offsets) - 1
+	if n == 0 {
+		return -1, 0, false
+	}
+
+	offset := start.TimeOffset
+	if offset < 0 {
+		offset += idx.Duration()
+	}
+
+	switch {
+	case offset < 0:
+		index, segmentOffset = 0, 0
+
+	case offset >= idx.Duration():
+		index, segmentOffset = n-1, idx.offsets[n]-idx.offsets[n-1]
+
+	default:
+		index, segmentOffset, _ = idx.Find(offset)
+
+	}
+
+	if !start.Precise {
+		segmentOffset = 0
+	}
+
+	return index, segmentOffset, true
+}
+
+// TotalDuration returns the sum of the durations of the Media Segments.
+func (p *MediaPlaylist) TotalDuration() time.Duration {
+	var total time.Duration
+	for _, segment := range p.Segments {
+		total += segment.Duration
+	}
+
+	return total
+}
+
+// SegmentOffset returns the offset of the start of the Media Segment at index
+// i of Segments from the start of the first one.
+//
+// Use SegmentIndex for repeated lookups.
+func (p *MediaPlaylist) SegmentOffset(i int) time.Duration {
+	var offset time.Duration
+	for _, segment := range p.Segments[:i] {
+		offset += segment.Duration
+	}
+
+	return offset
+}
+
+// FindSegment returns the index in Segments of the Media Segment that
+// contains the playback offset, along with the offset from the start of the
+// Media Segment.
+//
+// If no Media Segment contains the offset, ok is false.
+//
+// Use SegmentIndex for repeated lookups.
+func (p *MediaPlaylist) FindSegment(offset time.Duration) (index int, segmentOffset time.Duration, ok bool) {
+	return p.SegmentIndex().Find(offset)
+}
+
+// ResolveStart returns the index in Segments of the Media Segment at which
+// playback should start according to the Start value of the playlist, along
+// with the offset from the start of the Media Segment.
+//
+// If the playlist has no Start value or no Media Segments, ok is false.
+//
+// See SegmentIndex.ResolveStart.
+func (p *MediaPlaylist) ResolveStart() (index int, segmentOffset time.Duration, ok bool) {
+	if p.GenericPlaylist == nil || p.Start == nil {
+		return -1, 0, false
+	}
+
+	return p.SegmentIndex().ResolveStart(p.Start)
+}
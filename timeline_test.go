@@ -0,0 +1,154 @@
+package m3u8_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ssttevee/m3u8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2010, 2, 19, 14, 54, 23, 31000000, time.FixedZone("", 8*60*60))
+	for _, str := range []string{
+		"2010-02-19T14:54:23.031+08:00",
+		"2010-02-19T14:54:23.031+0800",
+		"2010-02-19T14:54:23.031+08",
+		"20100219T145423.031+0800",
+	} {
+		segment := &m3u8.MediaSegment{ProgramDateTime: str}
+
+		got, err := segment.ParseProgramDateTime()
+		if assert.Nil(t, err, str) {
+			assert.True(t, want.Equal(got), str)
+		}
+	}
+
+	dr := &m3u8.DateRange{StartDate: "2010-02-19T06:54:23Z"}
+
+	start, err := dr.ParseStartDate()
+	if assert.Nil(t, err) {
+		assert.True(t, start.Equal(want.Truncate(time.Second)))
+	}
+
+	end, err := dr.ParseEndDate()
+	assert.Nil(t, err)
+	assert.True(t, end.IsZero())
+
+	dr.StartDate = "2010-W07-5"
+	_, err = dr.ParseStartDate()
+	assert.NotNil(t, err)
+}
+
+func TestDateTimeline(t *testing.T) {
+	plist, err := m3u8.DecodePlaylist([]byte(`#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXTINF:4,
+0.ts
+#EXT-X-PROGRAM-DATE-TIME:2010-02-19T14:00:04Z
+#EXTINF:4,
+1.ts
+#EXTINF:2,
+2.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:4,
+3.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:4,
+4.ts
+#EXT-X-PROGRAM-DATE-TIME:2010-02-19T15:00:04Z
+#EXTINF:4,
+5.ts
+`))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	tl, err := plist.(*m3u8.MediaPlaylist).DateTimeline()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	base := time.Date(2010, 2, 19, 14, 0, 0, 0, time.UTC)
+	for i, want := range []time.Time{
+		base,
+		base.Add(4 * time.Second),
+		base.Add(8 * time.Second),
+		{},
+		base.Add(time.Hour),
+		base.Add(time.Hour + 4*time.Second),
+	} {
+		assert.True(t, want.Equal(tl.Start(i)), "segment %d", i)
+	}
+
+	index, offset, ok := tl.Locate(base.Add(9 * time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 2, index)
+	assert.Equal(t, time.Second, offset)
+
+	index, offset, ok = tl.Locate(base.Add(time.Hour + 5*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 5, index)
+	assert.Equal(t, time.Second, offset)
+
+	_, _, ok = tl.Locate(base.Add(10 * time.Second))
+	assert.False(t, ok)
+}
+
+func TestSegmentIndex(t *testing.T) {
+	plist, err := m3u8.DecodePlaylist([]byte(`#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:4
+#EXT-X-START:TIME-OFFSET=-5.5,PRECISE=YES
+#EXTINF:4,
+0.ts
+#EXTINF:3.5,
+1.ts
+#EXTINF:4,
+2.ts
+#EXT-X-ENDLIST
+`))
+	if !assert.Nil(t, err, "should sucessfully parse") {
+		t.FailNow()
+	}
+
+	p := plist.(*m3u8.MediaPlaylist)
+	idx := p.SegmentIndex()
+
+	assert.Equal(t, 11500*time.Millisecond, p.TotalDuration())
+	assert.Equal(t, p.TotalDuration(), idx.Duration())
+	assert.Equal(t, 7500*time.Millisecond, p.SegmentOffset(2))
+	assert.Equal(t, p.SegmentOffset(2), idx.Offset(2))
+
+	for offset, want := range map[time.Duration][2]time.Duration{
+		0:                        {0, 0},
+		4 * time.Second:          {1, 0},
+		7 * time.Second:          {1, 3 * time.Second},
+		11499 * time.Millisecond: {2, 3999 * time.Millisecond},
+	} {
+		index, segmentOffset, ok := p.FindSegment(offset)
+		assert.True(t, ok, "offset %s", offset)
+		assert.Equal(t, int(want[0]), index, "offset %s", offset)
+		assert.Equal(t, want[1], segmentOffset, "offset %s", offset)
+	}
+
+	_, _, ok := idx.Find(p.TotalDuration())
+	assert.False(t, ok)
+
+	index, segmentOffset, ok := p.ResolveStart()
+	assert.True(t, ok)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, 2*time.Second, segmentOffset)
+
+	index, segmentOffset, _ = idx.ResolveStart(&m3u8.Start{TimeOffset: 6 * time.Second})
+	assert.Equal(t, 1, index)
+	assert.Equal(t, time.Duration(0), segmentOffset)
+
+	index, segmentOffset, _ = idx.ResolveStart(&m3u8.Start{TimeOffset: -time.Minute, Precise: true})
+	assert.Equal(t, 0, index)
+	assert.Equal(t, time.Duration(0), segmentOffset)
+
+	index, segmentOffset, _ = idx.ResolveStart(&m3u8.Start{TimeOffset: time.Minute, Precise: true})
+	assert.Equal(t, 2, index)
+	assert.Equal(t, 4*time.Second, segmentOffset)
+}
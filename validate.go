@@ -0,0 +1,389 @@
+package m3u8
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity indicates how serious a Finding is.
+type Severity int
+
+const (
+	// SeverityError indicates a violation of a MUST or MUST NOT rule of the
+	// specification.
+	SeverityError Severity = iota + 1
+
+	// SeverityWarning indicates a violation of a SHOULD or SHOULD NOT rule of
+	// the specification.
+	SeverityWarning
+
+	// SeverityInfo indicates something that is allowed, but that may not be
+	// intended.
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+
+	panic("invalid severity")
+}
+
+// The rules that are checked by Validate.
+const (
+	RuleTargetDuration      = "target-duration"
+	RulePartTarget          = "part-target"
+	RuleDateRangeEndDate    = "date-range-end-date"
+	RuleDateRangeDuration   = "date-range-duration"
+	RuleDateRangeDateTime   = "date-range-program-date-time"
+	RuleProgramDateTime     = "program-date-time"
+	RuleVODEndList          = "vod-endlist"
+	RuleBandwidth           = "bandwidth"
+	RuleAverageBandwidth    = "average-bandwidth"
+	RuleCodecs              = "codecs"
+	RuleRenditionName       = "rendition-name"
+	RuleRenditionDefault    = "rendition-default"
+	RuleRenditionAutoSelect = "rendition-autoselect"
+	RuleRenditionGroup      = "rendition-group"
+	RuleKeyMethod           = "key-method"
+	RuleKeyFormat           = "key-format"
+	RuleKeyIV               = "key-iv"
+)
+
+// Finding is a problem that was found by Validate.
+type Finding struct {
+	Severity Severity
+
+	// Rule identifies the rule that was checked, such as RuleTargetDuration.
+	Rule string
+
+	// Line is the number of the line that the Finding refers to, where the
+	// header tag is on line 1, or zero if it does not refer to a line or if
+	// the playlist was not decoded.
+	Line int
+
+	Message string
+}
+
+func (f *Finding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s: %s (%s)", f.Severity, f.Message, f.Rule)
+	}
+
+	return fmt.Sprintf("line %d: %s: %s (%s)", f.Line, f.Severity, f.Message, f.Rule)
+}
+
+type findings []*Finding
+
+func (fs *findings) add(severity Severity, rule string, line int, msg string) {
+	*fs = append(*fs, &Finding{
+		Severity: severity,
+		Rule:     rule,
+		Line:     line,
+		Message:  msg,
+	})
+}
+
+// err returns the first finding with SeverityError as an error, if any.
+func (fs findings) err() error {
+	for _, f := range fs {
+		if f.Severity == SeverityError {
+			return &Error{f.Message}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the rules of the specification that are not enforced by
+// the Decoder, and returns the problems that were found, ordered by line.
+//
+// The Line values of the findings are only known for elements that were
+// decoded, since the lines are recorded by the Layout of the playlist.
+//
+// The media sequence numbers of the Media Segments of a playlist always
+// increase, since they are implied by the position of each segment. They can
+// only go backwards across reloads of a playlist, which is checked by
+// ValidateReload.
+func Validate(p Playlist) []*Finding {
+	var fs findings
+	switch p := p.(type) {
+	case *MediaPlaylist:
+		fs.checkMediaPlaylist(p)
+
+	case *MasterPlaylist:
+		fs.checkMasterPlaylist(p)
+
+	}
+
+	sort.SliceStable(fs, func(i, j int) bool {
+		return fs[i].Line < fs[j].Line
+	})
+
+	return fs
+}
+
+// layoutOf returns the Layout of a playlist, which is nil unless the
+// playlist was decoded.
+func layoutOf(p *GenericPlaylist) *Layout {
+	if p == nil {
+		return nil
+	}
+
+	return p.Layout
+}
+
+func (fs *findings) checkMediaPlaylist(p *MediaPlaylist) {
+	l := layoutOf(p.GenericPlaylist)
+	target := time.Duration(p.TargetDuration) * time.Second
+
+	var hasDateTime bool
+	var dateRanges []*DateRange
+	var prevDateTime time.Time
+	for _, segment := range p.Segments {
+		// the duration is rounded to the nearest integer number of seconds
+		if segment.Duration >= target+time.Second/2 {
+			fs.add(SeverityError, RuleTargetDuration, l.Line(segment), fmt.Sprintf("segment duration, %gs, exceeds the target duration, %ds", segment.Duration.Seconds(), p.TargetDuration))
+		}
+
+		fs.checkParts(l, segment.Parts, p.PartTarget)
+
+		if segment.Discontinuity {
+			prevDateTime = time.Time{}
+		}
+
+		if segment.ProgramDateTime != "" {
+			hasDateTime = true
+
+			if t, err := segment.ParseProgramDateTime(); err == nil {
+				if !prevDateTime.IsZero() && t.Before(prevDateTime) {
+					fs.add(SeverityWarning, RuleProgramDateTime, l.Line(&segment.ProgramDateTime), "program date time is earlier than the one of a previous segment without a discontinuity between them")
+				}
+
+				prevDateTime = t
+			}
+		}
+
+		for _, key := range segment.keys() {
+			fs.checkKey(key, l.Line(key))
+		}
+
+		if segment.DateRange != nil {
+			fs.checkDateRange(segment.DateRange, l.Line(segment.DateRange))
+			dateRanges = append(dateRanges, segment.DateRange)
+		}
+	}
+
+	fs.checkParts(l, p.Parts, p.PartTarget)
+
+	if !hasDateTime {
+		for _, dr := range dateRanges {
+			fs.add(SeverityError, RuleDateRangeDateTime, l.Line(dr), "date range in a playlist without a program date time")
+		}
+	}
+
+	if p.PlaylistType == VOD && p.Live {
+		fs.add(SeverityWarning, RuleVODEndList, 0, "playlist of type "+VOD.String()+" without an "+endlistTag+" tag")
+	}
+}
+
+func (fs *findings) checkParts(l *Layout, parts []*PartialSegment, target time.Duration) {
+	if target == 0 {
+		return
+	}
+
+	for _, part := range parts {
+		if part.Duration > target {
+			fs.add(SeverityError, RulePartTarget, l.Line(part), fmt.Sprintf("partial segment duration, %gs, exceeds the part target, %gs", part.Duration.Seconds(), target.Seconds()))
+		}
+	}
+}
+
+// checkDateRange checks the dates of a date range, where line is the line of
+// its tag, if any.
+func (fs *findings) checkDateRange(dr *DateRange, line int) {
+	if dr.EndDate == "" {
+		return
+	}
+
+	start, err := dr.ParseStartDate()
+	if err != nil {
+		return
+	}
+
+	end, err := dr.ParseEndDate()
+	if err != nil {
+		return
+	}
+
+	if end.Before(start) {
+		fs.add(SeverityError, RuleDateRangeEndDate, line, `date range, "`+dr.ID+`", ends before it starts`)
+	} else if dr.Duration > 0 && end.Sub(start) != dr.Duration {
+		fs.add(SeverityError, RuleDateRangeDuration, line, `date range, "`+dr.ID+`", has a duration that does not match its end date`)
+	}
+}
+
+// checkKey checks the pairing of the encryption method with the key format
+// and the IV of a key, where line is the line of its tag, if any.
+func (fs *findings) checkKey(key *Key, line int) {
+	switch key.Method {
+	case SampleAESCTR:
+		// the identity key format only defines keys for the AES-128 and
+		// SAMPLE-AES methods
+		if key.keyFormat() == identityKeyFormat {
+			fs.add(SeverityWarning, RuleKeyFormat, line, "key with method "+SampleAESCTR.String()+" uses the "+identityKeyFormat+" key format")
+		}
+
+		// the common encryption scheme carries the IVs of the samples in
+		// the media
+		if key.IV != nil {
+			fs.add(SeverityInfo, RuleKeyIV, line, "key with method "+SampleAESCTR.String()+" has an IV, which is carried by the media instead")
+		}
+
+	case UnknownEncryption:
+		fs.add(SeverityInfo, RuleKeyMethod, line, `key with unknown method, "`+key.MethodName+`"`)
+
+	}
+}
+
+func (fs *findings) checkMasterPlaylist(p *MasterPlaylist) {
+	l := layoutOf(p.GenericPlaylist)
+	fs.checkRenditions(l, p.RenditionMap)
+
+	for _, key := range p.SessionKeys {
+		fs.checkKey(key, l.Line(key))
+	}
+
+	for _, vs := range p.VariantStreams {
+		fs.checkStream(&vs.Stream, l.Line(vs))
+	}
+
+	for _, s := range p.IFrameStreams {
+		fs.checkStream(s, l.Line(s))
+	}
+
+	_, err := p.ResolveRenditions()
+	if errs, ok := err.(ErrorList); ok {
+		for _, err := range errs {
+			switch err := err.(type) {
+			case *DanglingGroupError:
+				fs.add(SeverityError, RuleRenditionGroup, p.streamLine(l, err.URI), strings.TrimPrefix(err.Error(), "m3u8: "))
+
+			case *UnusedGroupError:
+				fs.add(SeverityInfo, RuleRenditionGroup, p.groupLine(l, err.Type, err.GroupID), strings.TrimPrefix(err.Error(), "m3u8: "))
+
+			}
+		}
+	}
+}
+
+// checkStream checks the attributes of a stream, where line is the line of its
+// tag, if any.
+func (fs *findings) checkStream(s *Stream, line int) {
+	if s.Bandwidth == 0 {
+		fs.add(SeverityError, RuleBandwidth, line, "missing bandwidth")
+	} else if s.AverageBandwidth > s.Bandwidth {
+		fs.add(SeverityWarning, RuleAverageBandwidth, line, "average bandwidth exceeds the peak bandwidth")
+	}
+
+	if len(s.Codecs) == 0 {
+		fs.add(SeverityWarning, RuleCodecs, line, "missing codecs")
+	}
+}
+
+// checkRenditions checks the rules of rendition groups.
+//
+// See https://tools.ietf.org/html/rfc8216#section-4.3.4.1.1.
+func (fs *findings) checkRenditions(l *Layout, rs []Rendition) {
+	type renditionGroup struct {
+		hasDefault bool
+		names      map[string]bool
+		selections map[string]bool
+	}
+
+	groups := map[groupKey]*renditionGroup{}
+	for _, r := range rs {
+		key := groupKey{r.Type(), r.groupID()}
+		group, ok := groups[key]
+		if !ok {
+			group = &renditionGroup{
+				names:      map[string]bool{},
+				selections: map[string]bool{},
+			}
+
+			groups[key] = group
+		}
+
+		line := l.Line(r.basic())
+
+		if group.names[r.name()] {
+			fs.add(SeverityError, RuleRenditionName, line, "all renditions in the same group must have different names")
+		}
+
+		group.names[r.name()] = true
+
+		if r.isDefault() {
+			if group.hasDefault {
+				fs.add(SeverityError, RuleRenditionDefault, line, "a rendition group must not have more than one default")
+			}
+
+			group.hasDefault = true
+		}
+
+		if r.isAutoSelect() {
+			selection := autoSelection(r)
+			if group.selections[selection] {
+				fs.add(SeverityWarning, RuleRenditionAutoSelect, line, "renditions that are selected automatically should differ in language, forced or characteristics")
+			}
+
+			group.selections[selection] = true
+		}
+	}
+}
+
+// autoSelection returns the attributes that distinguish a Rendition that is
+// selected automatically from the other members of its group.
+func autoSelection(r Rendition) string {
+	b := r.basic()
+
+	var forced bool
+	if s, ok := r.(*SubtitlesRendition); ok {
+		forced = s.Forced
+	}
+
+	return fmt.Sprintf("%q %q %t %q", b.Language, b.AssociatedLanguage, forced, b.Characteristics)
+}
+
+func (p *MasterPlaylist) streamLine(l *Layout, uri string) int {
+	for _, vs := range p.VariantStreams {
+		if vs.URI == uri {
+			return l.Line(vs)
+		}
+	}
+
+	for _, s := range p.IFrameStreams {
+		if s.URI == uri {
+			return l.Line(s)
+		}
+	}
+
+	return 0
+}
+
+func (p *MasterPlaylist) groupLine(l *Layout, t MediaType, groupID string) int {
+	for _, r := range p.RenditionMap {
+		if r.Type() == t && r.groupID() == groupID {
+			return l.Line(r.basic())
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,175 @@
+package m3u8_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ssttevee/m3u8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	type finding struct {
+		severity m3u8.Severity
+		rule     string
+		line     int
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []finding
+	}{
+		{
+			name: "valid media playlist",
+			data: "#EXTM3U\n" +
+				"#EXT-X-VERSION:3\n" +
+				"#EXT-X-TARGETDURATION:10\n" +
+				"#EXT-X-PLAYLIST-TYPE:VOD\n" +
+				"#EXTINF:10.4,\n" +
+				"first.ts\n" +
+				"#EXT-X-ENDLIST\n",
+		},
+		{
+			name: "media playlist",
+			data: "#EXTM3U\n" +
+				"#EXT-X-VERSION:3\n" +
+				"#EXT-X-TARGETDURATION:10\n" +
+				"#EXT-X-PLAYLIST-TYPE:VOD\n" +
+				"#EXTINF:10.5,\n" +
+				"first.ts\n" +
+				"#EXT-X-DATERANGE:ID=\"ad\",START-DATE=\"2020-01-01T00:00:10Z\",END-DATE=\"2020-01-01T00:00:00Z\"\n" +
+				"#EXTINF:9.009,\n" +
+				"second.ts\n",
+			expected: []finding{
+				{m3u8.SeverityWarning, m3u8.RuleVODEndList, 0},
+				{m3u8.SeverityError, m3u8.RuleTargetDuration, 5},
+				{m3u8.SeverityError, m3u8.RuleDateRangeEndDate, 7},
+				{m3u8.SeverityError, m3u8.RuleDateRangeDateTime, 7},
+			},
+		},
+		{
+			name: "program date time",
+			data: "#EXTM3U\n" +
+				"#EXT-X-TARGETDURATION:10\n" +
+				"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:10Z\n" +
+				"#EXTINF:10,\n" +
+				"first.ts\n" +
+				"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z\n" +
+				"#EXTINF:10,\n" +
+				"second.ts\n" +
+				"#EXT-X-DISCONTINUITY\n" +
+				"#EXT-X-PROGRAM-DATE-TIME:2019-01-01T00:00:00Z\n" +
+				"#EXTINF:10,\n" +
+				"third.ts\n",
+			expected: []finding{
+				{m3u8.SeverityWarning, m3u8.RuleProgramDateTime, 6},
+			},
+		},
+		{
+			name: "key methods",
+			data: "#EXTM3U\n" +
+				"#EXT-X-VERSION:5\n" +
+				"#EXT-X-TARGETDURATION:10\n" +
+				"#EXT-X-KEY:METHOD=SAMPLE-AES-CTR,URI=\"a.key\"\n" +
+				"#EXTINF:10,\n" +
+				"first.ts\n" +
+				"#EXT-X-KEY:METHOD=SAMPLE-AES-CTR,URI=\"data:text/plain;base64,AAAA\",KEYFORMAT=\"urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed\"\n" +
+				"#EXTINF:10,\n" +
+				"second.ts\n" +
+				"#EXT-X-KEY:METHOD=SAMPLE-AES-CTR,URI=\"data:text/plain;base64,AAAA\",KEYFORMAT=\"urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed\",IV=0x1\n" +
+				"#EXTINF:10,\n" +
+				"third.ts\n",
+			expected: []finding{
+				{m3u8.SeverityWarning, m3u8.RuleKeyFormat, 4},
+				{m3u8.SeverityInfo, m3u8.RuleKeyIV, 10},
+			},
+		},
+		{
+			name: "master playlist",
+			data: "#EXTM3U\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",NAME=\"English\",LANGUAGE=\"en\",DEFAULT=YES,AUTOSELECT=YES,URI=\"en.m3u8\"\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",NAME=\"English\",LANGUAGE=\"en\",DEFAULT=YES,AUTOSELECT=YES,URI=\"en2.m3u8\"\n" +
+				"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=\"English\",URI=\"en.vtt.m3u8\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1280000,AVERAGE-BANDWIDTH=2560000,AUDIO=\"aac\",VIDEO=\"vid\"\n" +
+				"low.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=2560000,CODECS=\"avc1.4d401f,mp4a.40.2\",AUDIO=\"aac\"\n" +
+				"high.m3u8\n",
+			expected: []finding{
+				{m3u8.SeverityError, m3u8.RuleRenditionName, 3},
+				{m3u8.SeverityError, m3u8.RuleRenditionDefault, 3},
+				{m3u8.SeverityWarning, m3u8.RuleRenditionAutoSelect, 3},
+				{m3u8.SeverityInfo, m3u8.RuleRenditionGroup, 4},
+				{m3u8.SeverityWarning, m3u8.RuleAverageBandwidth, 5},
+				{m3u8.SeverityWarning, m3u8.RuleCodecs, 5},
+				{m3u8.SeverityError, m3u8.RuleRenditionGroup, 5},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plist, err := m3u8.DecodePlaylist([]byte(test.data))
+			if !assert.Nil(t, err, "should sucessfully parse") {
+				t.FailNow()
+			}
+
+			var actual []finding
+			for _, f := range m3u8.Validate(plist) {
+				actual = append(actual, finding{f.Severity, f.Rule, f.Line})
+			}
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+
+	t.Run("unknown key method", func(t *testing.T) {
+		d := m3u8.NewDecoder(strings.NewReader("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXT-X-KEY:METHOD=AES-256,URI=\"a.key\"\n#EXTINF:10,\nfirst.ts\n"))
+		d.Strict = false
+
+		plist, err := d.Decode()
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		findings := m3u8.Validate(plist)
+		if assert.Len(t, findings, 1) {
+			assert.Equal(t, m3u8.SeverityInfo, findings[0].Severity)
+			assert.Equal(t, m3u8.RuleKeyMethod, findings[0].Rule)
+			assert.Equal(t, 3, findings[0].Line)
+		}
+	})
+
+	t.Run("decoded in lossless mode", func(t *testing.T) {
+		d := m3u8.NewDecoder(strings.NewReader(tests[1].data))
+		d.Lossless = true
+
+		plist, err := d.Decode()
+		if !assert.Nil(t, err, "should sucessfully parse") {
+			t.FailNow()
+		}
+
+		var actual []finding
+		for _, f := range m3u8.Validate(plist) {
+			actual = append(actual, finding{f.Severity, f.Rule, f.Line})
+		}
+
+		assert.Equal(t, tests[1].expected, actual)
+	})
+
+	t.Run("constructed master playlist", func(t *testing.T) {
+		findings := m3u8.Validate(&m3u8.MasterPlaylist{
+			GenericPlaylist: &m3u8.GenericPlaylist{},
+			VariantStreams: []*m3u8.VariantStream{
+				{Stream: m3u8.Stream{URI: "low.m3u8", Codecs: []string{"avc1.4d401f"}}},
+			},
+		})
+
+		if assert.Len(t, findings, 1) {
+			assert.Equal(t, m3u8.SeverityError, findings[0].Severity)
+			assert.Equal(t, m3u8.RuleBandwidth, findings[0].Rule)
+			assert.Equal(t, 0, findings[0].Line)
+			assert.Equal(t, "error: missing bandwidth (bandwidth)", findings[0].String())
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package m3u8
+
+import (
+	"strings"
+	"time"
+)
+
+// RequiredVersion returns the minimum compatibility version number that is
+// required by the features used in the playlist, according to the protocol
+// version compatibility rules of the specification.
+//
+// See https://tools.ietf.org/html/draft-pantos-hls-rfc8216bis-13#section-8.
+func RequiredVersion(p Playlist) int {
+	switch p := p.(type) {
+	case *MediaPlaylist:
+		return p.requiredVersion()
+
+	case *MasterPlaylist:
+		return p.requiredVersion()
+
+	}
+
+	return 1
+}
+
+// versionRequirement tracks the highest version required by a playlist.
+type versionRequirement int
+
+func (v *versionRequirement) require(version int) {
+	if int(*v) < version {
+		*v = versionRequirement(version)
+	}
+}
+
+func (p *GenericPlaylist) requiredVersion() versionRequirement {
+	v := versionRequirement(1)
+	if p == nil {
+		return v
+	}
+
+	for _, def := range p.Definitions {
+		v.require(8)
+
+		if def.QueryParam {
+			v.require(11)
+		}
+	}
+
+	return v
+}
+
+func (k *Key) requiredVersion() int {
+	switch {
+	case k.KeyFormat != "" || len(k.KeyFormatVersions) > 0 || k.Method == SampleAES || k.Method == SampleAESCTR:
+		return 5
+
+	case k.IV != nil:
+		return 2
+
+	}
+
+	return 1
+}
+
+func (p *MediaPlaylist) requiredVersion() int {
+	v := p.GenericPlaylist.requiredVersion()
+
+	if p.IFramesOnly {
+		v.require(4)
+	}
+
+	if p.Skip != nil {
+		v.require(9)
+
+		if p.Skip.DateRangesSkipped || len(p.Skip.RecentlyRemovedDateRanges) > 0 {
+			v.require(10)
+		}
+	}
+
+	for _, segment := range p.Segments {
+		if segment.Duration%time.Second != 0 {
+			v.require(3)
+		}
+
+		if segment.ByteRange != nil {
+			v.require(4)
+		}
+
+		for _, key := range segment.keys() {
+			v.require(key.requiredVersion())
+		}
+
+		if segment.Map != nil {
+			if p.IFramesOnly {
+				v.require(5)
+			} else {
+				v.require(6)
+			}
+		}
+	}
+
+	return int(v)
+}
+
+func (p *MasterPlaylist) requiredVersion() int {
+	v := p.GenericPlaylist.requiredVersion()
+
+	for _, key := range p.SessionKeys {
+		v.require(key.requiredVersion())
+	}
+
+	for _, r := range p.RenditionMap {
+		if cc, ok := r.(*ClosedCaptionsRendition); ok && strings.HasPrefix(cc.InstreamID, "SERVICE") {
+			v.require(7)
+		}
+	}
+
+	for _, vs := range p.VariantStreams {
+		if len(vs.ReqVideoLayout) > 0 {
+			v.require(12)
+		}
+	}
+
+	for _, s := range p.IFrameStreams {
+		if len(s.ReqVideoLayout) > 0 {
+			v.require(12)
+		}
+	}
+
+	return int(v)
+}